@@ -0,0 +1,74 @@
+package squad
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+)
+
+// memberLabel is pprof label of squad member, see Squad.labeled.
+const memberLabel = "squad.member"
+
+// Goroutines returns number of goroutines owned by each running squad member including
+// goroutines started by member, so it helps to find member responsible for growth.
+// Goroutines are attributed by pprof labels sampled from goroutine profile, so members
+// of squad with WithLowOverhead are not accounted, and members with the same name
+// in several squads of one process are accounted together, see MemberStatus.Goroutines.
+func (s *Squad) Goroutines() map[string]int {
+	goroutines := make(map[string]int)
+	for _, status := range s.Describe() {
+		if status.Goroutines > 0 {
+			goroutines[status.Name] = status.Goroutines
+		}
+	}
+	return goroutines
+}
+
+// memberGoroutines counts goroutines of members by their pprof labels,
+// goroutines are not labeled in squad with WithLowOverhead.
+func (s *Squad) memberGoroutines() map[string]int {
+	if s.lowOverhead {
+		return nil
+	}
+	var profile bytes.Buffer
+	_ = pprof.Lookup("goroutine").WriteTo(&profile, 1)
+	return countGoroutines(&profile)
+}
+
+// countGoroutines counts goroutines of textual goroutine profile by squad member label.
+func countGoroutines(profile io.Reader) map[string]int {
+	counts := make(map[string]int)
+
+	var count int
+	scanner := bufio.NewScanner(profile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if n, _, ok := strings.Cut(line, " @ "); ok {
+			count, _ = strconv.Atoi(n)
+			continue
+		}
+		if labels, ok := strings.CutPrefix(line, "# labels: "); ok {
+			if member, ok := labelValue(labels, memberLabel); ok {
+				counts[member] += count
+			}
+		}
+	}
+	return counts
+}
+
+// labelValue returns value of label from labels formatted as {"key":"value", ...}.
+func labelValue(labels, key string) (string, bool) {
+	_, rest, ok := strings.Cut(labels, strconv.Quote(key)+":")
+	if !ok {
+		return "", false
+	}
+	quoted, err := strconv.QuotedPrefix(rest)
+	if err != nil {
+		return "", false
+	}
+	value, err := strconv.Unquote(quoted)
+	return value, err == nil
+}
@@ -0,0 +1,183 @@
+// Package amqp contains adapter of AMQP channel consumers, e.g. rabbitmq/amqp091-go,
+// to squad consumers, without dependency on AMQP client.
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moeryomenko/squad"
+)
+
+// ErrDeliveriesClosed is returned by consumer, when deliveries are closed
+// before squad begins shutdown, e.g. by closing of channel.
+var ErrDeliveriesClosed = errors.New("deliveries closed")
+
+// Delivery is an AMQP delivery, e.g. amqp.Delivery.
+type Delivery interface {
+	Ack(multiple bool) error
+	Nack(multiple, requeue bool) error
+}
+
+// Canceler cancels consumer by its tag, e.g. *amqp.Channel.
+type Canceler interface {
+	Cancel(consumer string, noWait bool) error
+}
+
+// RunConsumer runs deliveries of consumer with given tag as squad consumer, e.g.
+//
+//	deliveries, err := ch.Consume(queue, tag, false, false, false, false, nil)
+//	...
+//	amqp.RunConsumer(s, ch, tag, deliveries, handle)
+//
+// Every delivery is handled concurrently within handle context, acked on success and
+// nacked with requeue on error. When squad begins shutdown consumer tag is canceled,
+// buffered deliveries are requeued and unacked deliveries are awaited during grace
+// period. Deliveries left when squad context is canceled are requeued and their handle
+// context is canceled, squad waits it within shutdown timeout, see squad.WithStopTimeout.
+func RunConsumer[D Delivery](s *squad.Squad, ch Canceler, tag string, deliveries <-chan D, handle func(context.Context, D) error, opts ...squad.TaskOption) {
+	s.RunConsumer(func(consumeCtx, handleCtx context.Context) error {
+		ctx, abort := context.WithCancel(handleCtx)
+		defer abort()
+
+		c := &consumer[D]{handle: handle, unacked: make(map[*delivery[D]]struct{})}
+		err := c.consume(ctx, consumeCtx, s.Draining(), deliveries)
+		if cancelErr := ch.Cancel(tag, false); cancelErr != nil {
+			err = errors.Join(err, fmt.Errorf("cancel consumer %s: %w", tag, cancelErr))
+		}
+		c.requeueBuffered(deliveries)
+
+		if !c.wait(s.DrainTimer().Remaining()) {
+			abort()
+			c.requeueUnacked()
+		}
+		return errors.Join(err, c.err())
+	}, append([]squad.TaskOption{squad.WithStopTimeout(s.ShutdownTimeout())}, opts...)...)
+}
+
+// consumer handles deliveries and tracks unacked ones.
+type consumer[D Delivery] struct {
+	handle func(context.Context, D) error
+	wg     sync.WaitGroup
+
+	mtx     sync.Mutex
+	unacked map[*delivery[D]]struct{}
+	errs    []error
+}
+
+// delivery is a delivery, which is acked or nacked once.
+type delivery[D Delivery] struct {
+	d    D
+	once sync.Once
+}
+
+// consume starts handling of deliveries until squad begins shutdown.
+func (c *consumer[D]) consume(ctx, consumeCtx context.Context, draining <-chan struct{}, deliveries <-chan D) error {
+	for {
+		select {
+		case <-consumeCtx.Done():
+			return nil
+		case <-draining:
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				return ErrDeliveriesClosed
+			}
+			c.start(ctx, d)
+		}
+	}
+}
+
+func (c *consumer[D]) start(ctx context.Context, d D) {
+	tracked := &delivery[D]{d: d}
+	c.mtx.Lock()
+	c.unacked[tracked] = struct{}{}
+	c.mtx.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		err := c.handle(ctx, d)
+		c.settle(tracked, func(d D) error {
+			if err != nil {
+				return d.Nack(false, true)
+			}
+			return d.Ack(false)
+		})
+	}()
+}
+
+// settle acks or nacks delivery, unless it has been settled already.
+func (c *consumer[D]) settle(tracked *delivery[D], fn func(D) error) {
+	tracked.once.Do(func() {
+		err := fn(tracked.d)
+
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		delete(c.unacked, tracked)
+		if err != nil {
+			c.errs = append(c.errs, err)
+		}
+	})
+}
+
+// requeueBuffered requeues received, but not yet handled deliveries.
+func (c *consumer[D]) requeueBuffered(deliveries <-chan D) {
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			c.settle(&delivery[D]{d: d}, requeue[D])
+		default:
+			return
+		}
+	}
+}
+
+// wait waits unacked deliveries for timeout and reports whether all of them have been handled.
+func (c *consumer[D]) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// requeueUnacked requeues deliveries, handling of which has not been completed.
+func (c *consumer[D]) requeueUnacked() {
+	c.mtx.Lock()
+	unacked := make([]*delivery[D], 0, len(c.unacked))
+	for tracked := range c.unacked {
+		unacked = append(unacked, tracked)
+	}
+	c.mtx.Unlock()
+
+	for _, tracked := range unacked {
+		c.settle(tracked, requeue[D])
+	}
+}
+
+func (c *consumer[D]) err() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return errors.Join(c.errs...)
+}
+
+func requeue[D Delivery](d D) error {
+	return d.Nack(false, true)
+}
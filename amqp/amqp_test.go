@@ -0,0 +1,107 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+// testAcks records settlement of test deliveries.
+type testAcks struct {
+	mtx     sync.Mutex
+	settled map[string]string
+}
+
+func (a *testAcks) record(body, outcome string) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.settled[body] = outcome
+	return nil
+}
+
+func (a *testAcks) outcomes() map[string]string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.settled
+}
+
+type testDelivery struct {
+	body string
+	acks *testAcks
+}
+
+func (d testDelivery) Ack(bool) error {
+	return d.acks.record(d.body, "ack")
+}
+
+func (d testDelivery) Nack(_, requeue bool) error {
+	if requeue {
+		return d.acks.record(d.body, "requeue")
+	}
+	return d.acks.record(d.body, "nack")
+}
+
+type testChannel struct {
+	canceled chan string
+}
+
+func (ch *testChannel) Cancel(consumer string, _ bool) error {
+	ch.canceled <- consumer
+	return nil
+}
+
+func TestRunConsumer(t *testing.T) {
+	s, err := squad.New(squad.WithManualTrigger(
+		squad.WithGracefulPeriod(300*time.Millisecond),
+		squad.WithShutdownTimeout(100*time.Millisecond),
+	))
+	assert.NoError(t, err)
+
+	acks := &testAcks{settled: make(map[string]string)}
+	ch := &testChannel{canceled: make(chan string, 1)}
+	deliveries := make(chan testDelivery)
+	errHandle := errors.New("handle failed")
+	handling := make(chan struct{}, 3)
+	RunConsumer(s, ch, "worker", deliveries, func(ctx context.Context, d testDelivery) error {
+		handling <- struct{}{}
+		switch d.body {
+		case "slow":
+			// NOTE: unacked delivery is awaited during grace period.
+			time.Sleep(100 * time.Millisecond)
+		case "hung":
+			<-ctx.Done()
+			return ctx.Err()
+		case "bad":
+			return errHandle
+		}
+		return nil
+	})
+
+	for _, body := range []string{"slow", "hung", "bad"} {
+		deliveries <- testDelivery{body: body, acks: acks}
+		<-handling
+	}
+	s.Stop(nil)
+
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, "worker", <-ch.canceled)
+	assert.Equal(t, map[string]string{"slow": "ack", "hung": "requeue", "bad": "requeue"}, acks.outcomes())
+}
+
+func TestRunConsumer_DeliveriesClosed(t *testing.T) {
+	s, err := squad.New()
+	assert.NoError(t, err)
+
+	ch := &testChannel{canceled: make(chan string, 1)}
+	deliveries := make(chan testDelivery)
+	close(deliveries)
+	RunConsumer(s, ch, "worker", deliveries, func(context.Context, testDelivery) error { return nil })
+
+	assert.ErrorIs(t, s.Wait(), ErrDeliveriesClosed)
+}
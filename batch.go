@@ -0,0 +1,34 @@
+package squad
+
+// WithRunUntilComplete is a Squad option for batch jobs and one-shot commands:
+// squad begins shutdown as soon as all members launched by Run, RunGracefully
+// and RunSupervised have completed, while servers, consumers and refreshers
+// are stopped by shutdown. Cleanup functions and timeouts are honored as usual.
+func WithRunUntilComplete() Option {
+	return func(s *Squad) {
+		s.runUntilComplete = true
+	}
+}
+
+// ExitCode maps result of Squad.Wait to process exit code.
+func ExitCode(err error) int {
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// stopOnComplete begins shutdown after all jobs have completed.
+func (s *Squad) stopOnComplete() {
+	if !s.runUntilComplete {
+		return
+	}
+
+	go func() {
+		select {
+		case <-s.ctx.Done():
+		case <-waitDone(&s.jobs):
+			s.beginShutdown(ShutdownReason{Kind: ReasonCompleted}, nil)
+		}
+	}()
+}
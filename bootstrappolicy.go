@@ -0,0 +1,109 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// bootstrapRetryBackoff is pause before first retry of failed bootstrap.
+	bootstrapRetryBackoff = 100 * time.Millisecond
+	// maxBootstrapRetryBackoff limits exponential growth of bootstrap retry backoff.
+	maxBootstrapRetryBackoff = 30 * time.Second
+)
+
+// ErrNotBootstrapped is reported by Squad.Ready, when bootstrap has failed.
+var ErrNotBootstrapped = errors.New("bootstrap failed")
+
+// BootstrapFailurePolicy defines reaction of New on bootstrap failure.
+type BootstrapFailurePolicy int
+
+const (
+	// BootstrapFail returns bootstrap error from New.
+	BootstrapFail BootstrapFailurePolicy = iota + 1
+	// BootstrapRetry returns squad from New and retries bootstrap with exponential
+	// backoff until it succeeds or squad begins shutdown, members are launched
+	// after successful bootstrap. Bootstrap functions must be idempotent.
+	BootstrapRetry
+	// BootstrapDegraded returns squad from New without launching members,
+	// so only health endpoint is served and explains failure.
+	BootstrapDegraded
+)
+
+// WithBootstrapFailurePolicy is a Squad option that sets reaction on failure
+// of bootstrap in New, by default it is BootstrapFail. Until bootstrap succeeds
+// Squad.Ready reports ErrNotBootstrapped with cause of failure.
+func WithBootstrapFailurePolicy(policy BootstrapFailurePolicy) Option {
+	return func(s *Squad) {
+		s.bootstrapPolicy = policy
+	}
+}
+
+// bootstrapFailed applies bootstrap failure policy, it returns error
+// if New must fail.
+func (s *Squad) bootstrapFailed(ctx context.Context, err error) error {
+	switch s.bootstrapPolicy {
+	case BootstrapRetry:
+		s.setBootstrapErr(err)
+		s.bootstrapped = make(chan struct{})
+		go s.retryLaunch(ctx)
+		return nil
+	case BootstrapDegraded:
+		s.setBootstrapErr(err)
+		s.bootstrapped = make(chan struct{})
+		return nil
+	default:
+		return err
+	}
+}
+
+// waitBootstrap blocks until failed bootstrap succeeds or squad begins shutdown,
+// it returns cause of failure, if bootstrap has not succeeded.
+func (s *Squad) waitBootstrap() error {
+	if s.bootstrapped == nil {
+		return nil
+	}
+
+	select {
+	case <-s.bootstrapped:
+	case <-s.drainContext().Done():
+	case <-s.ctx.Done():
+	}
+	return s.bootstrapReady()
+}
+
+// retryLaunch retries launch until it succeeds or squad begins shutdown.
+func (s *Squad) retryLaunch(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(s.drainContext(), cancel)()
+
+	backoff := Backoff{Initial: bootstrapRetryBackoff, Max: maxBootstrapRetryBackoff}
+	for retries := 0; waitBackoff(ctx, backoff.delay(retries)); retries++ {
+		err := s.launch(ctx)
+		s.setBootstrapErr(err)
+		if err == nil {
+			close(s.bootstrapped)
+			return
+		}
+	}
+}
+
+func (s *Squad) setBootstrapErr(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.bootstrapErr = err
+}
+
+// bootstrapReady reports cause of bootstrap failure, if any.
+func (s *Squad) bootstrapReady() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.bootstrapErr != nil {
+		return fmt.Errorf("%w: %w", ErrNotBootstrapped, s.bootstrapErr)
+	}
+	return nil
+}
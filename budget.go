@@ -0,0 +1,49 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetExceeded is returned by Squad.ShutdownBudget,
+// when reserved slices exceed shutdown timeout.
+var ErrBudgetExceeded = errors.New("shutdown budget exceeded")
+
+// Budget is named slice of shutdown timeout reserved by library for its cleanups.
+type Budget struct {
+	s        *Squad
+	name     string
+	duration time.Duration
+}
+
+// ShutdownBudget reserves named fraction of shutdown timeout, so several independent
+// libraries do not each assume they own the full timeout. Fractions of all budgets
+// must not exceed 1, otherwise ErrBudgetExceeded is returned.
+func (s *Squad) ShutdownBudget(name string, fraction float64) (*Budget, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if fraction <= 0 || s.reserved+fraction > 1 {
+		return nil, fmt.Errorf("%w: %s requests %v of shutdown timeout, %v is reserved", ErrBudgetExceeded, name, fraction, s.reserved)
+	}
+	s.reserved += fraction
+
+	return &Budget{s: s, name: name, duration: time.Duration(fraction * float64(s.cancellationDelay))}, nil
+}
+
+// Name returns name of budget.
+func (b *Budget) Name() string {
+	return b.name
+}
+
+// Context returns context, which deadline is end of budget slice counted
+// since cancellation of squad context, or since now if squad is running.
+func (b *Budget) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	start := time.Now()
+	if nanos := b.s.canceledAt.Load(); nanos != 0 {
+		start = time.Unix(0, nanos)
+	}
+	return context.WithDeadline(parent, start.Add(b.duration))
+}
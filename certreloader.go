@@ -0,0 +1,120 @@
+package squad
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CertReloader is TLS certificate loaded from certificate and key files, which can be
+// swapped without restart of server by GetCertificate of tls.Config, e.g.
+//
+//	certs, err := squad.NewCertReloader(certFile, keyFile)
+//	s, err := squad.New(squad.WithReloader(certs.Reload))
+//	s.WatchCertificates(certs, time.Minute)
+//	s.RunServerTLS(&http.Server{TLSConfig: &tls.Config{GetCertificate: certs.GetCertificate}}, "", "")
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	cert atomic.Pointer[tls.Certificate]
+	// mtx serializes reloads, stamp is stamp of files of loaded certificate.
+	mtx   sync.Mutex
+	stamp [2]fileStamp
+}
+
+// NewCertReloader returns CertReloader with certificate loaded from given files.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns last loaded certificate, it is suitable for tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Reload loads certificate from files, it is suitable for WithReloader, so certificate
+// is reloaded on SIGHUP. Failed reload keeps serving previous certificate.
+func (r *CertReloader) Reload(context.Context) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.reload(r.files())
+}
+
+func (r *CertReloader) reload(stamp [2]fileStamp) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert.Store(&cert)
+	r.stamp = stamp
+	return nil
+}
+
+// reloadChanged reloads certificate, if its files have been changed since last load.
+func (r *CertReloader) reloadChanged() (bool, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	stamp := r.files()
+	if stamp == r.stamp {
+		return false, nil
+	}
+	return true, r.reload(stamp)
+}
+
+func (r *CertReloader) files() [2]fileStamp {
+	return [2]fileStamp{statFile(r.certFile), statFile(r.keyFile)}
+}
+
+// fileStamp identifies version of file by its modification time and size.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// WatchCertificates runs watcher of certificate files, which reloads certificate every
+// interval if files have been changed, e.g. renewed by cert-manager. Failed reload does not
+// stop squad, it is retried on next check, since files may be written one after another.
+// Watcher keeps running during drain, since servers keep accepting TLS handshakes,
+// and stops when squad context is canceled.
+func (s *Squad) WatchCertificates(r *CertReloader, interval time.Duration, opts ...TaskOption) {
+	t := s.newTask(opts)
+	t.background = true
+
+	s.goTask(t, func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			switch changed, err := r.reloadChanged(); {
+			case err != nil:
+				s.log(slog.LevelError, "certificate reload failed", "task", t.name, "error", err)
+			case changed:
+				s.log(slog.LevelInfo, "certificate reloaded", "task", t.name)
+			}
+		}
+	})
+}
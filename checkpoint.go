@@ -0,0 +1,67 @@
+package squad
+
+import (
+	"context"
+	"fmt"
+)
+
+// Checkpointer is implemented by interruptible jobs, which can save their progress
+// to resume after restart.
+type Checkpointer interface {
+	// Checkpoint returns payload of progress made by job.
+	Checkpoint(ctx context.Context) ([]byte, error)
+}
+
+// WithCheckpointer sets checkpointer of squad member, which is invoked
+// when squad begins shutdown, see WithCheckpointHandler.
+func WithCheckpointer(checkpointer Checkpointer) TaskOption {
+	return func(t *task) {
+		t.checkpointer = checkpointer
+	}
+}
+
+// WithCheckpointHandler is a Squad option that sets handler persisting
+// checkpoints of members. Checkpoints are taken within hard deadline
+// before cleanup functions.
+func WithCheckpointHandler(handler func(ctx context.Context, task string, payload []byte) error) Option {
+	return func(s *Squad) {
+		s.checkpointHandler = handler
+	}
+}
+
+// watchCheckpoint takes checkpoint of member, if it is still running when squad begins shutdown.
+func (s *Squad) watchCheckpoint(t *task, done <-chan struct{}) {
+	s.stopping.Add(1)
+
+	go func() {
+		defer s.stopping.Done()
+
+		select {
+		case <-done:
+			return
+		case <-s.drainContext().Done():
+		case <-s.ctx.Done():
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		// NOTE: checkpoint is bounded by hard deadline counted since beginning of shutdown,
+		// so slow checkpoint does not outlive cleanup functions waiting it.
+		ctx, cancel := context.WithDeadline(context.WithoutCancel(s.ctx), s.hardDeadline())
+		defer cancel()
+
+		s.appendErr(s.labeled(ctx, t.name, phaseDrain, func(ctx context.Context) error {
+			payload, err := t.checkpointer.Checkpoint(ctx)
+			if err == nil {
+				err = s.checkpointHandler(ctx, t.name, payload)
+			}
+			if err != nil {
+				return fmt.Errorf("checkpoint of task %s: %w", t.name, err)
+			}
+			return nil
+		}))
+	}()
+}
@@ -0,0 +1,148 @@
+package squad
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Report is a final report of squad shutdown.
+type Report struct {
+	Cleanups []CleanupReport
+}
+
+// CleanupReport describes execution of cleanup function.
+type CleanupReport struct {
+	// Name is subsystem name, empty for anonymous cleanup functions.
+	Name string
+	// Ran reports whether cleanup function has been called.
+	Ran bool
+	// Attempts is number of calls of cleanup function including retries.
+	Attempts int
+	Err      error
+	Duration time.Duration
+}
+
+// Report returns report of squad shutdown, it is complete after Wait returns.
+func (s *Squad) Report() Report {
+	report := Report{Cleanups: make([]CleanupReport, 0, len(s.cancellationFuncs))}
+	for _, c := range s.cancellationFuncs {
+		report.Cleanups = append(report.Cleanups, c.result())
+	}
+	for _, node := range s.dependents {
+		report.Cleanups = append(report.Cleanups, node.cleanup.result())
+	}
+	for _, phase := range s.phases {
+		for _, c := range phase.cleanups {
+			report.Cleanups = append(report.Cleanups, c.result())
+		}
+	}
+	return report
+}
+
+// AtExit adds callback, which is invoked with final report after all cleanup
+// functions have finished, but before Wait returns, e.g. to emit audit event
+// or write shutdown marker file. Callbacks are invoked in order of adding.
+func (s *Squad) AtExit(fn func(report Report)) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.exitHooks = append(s.exitHooks, fn)
+}
+
+// runExitHooks invokes exit callbacks, panic of callback is reported as squad error.
+func (s *Squad) runExitHooks() {
+	s.mtx.Lock()
+	hooks := slices.Clone(s.exitHooks)
+	s.mtx.Unlock()
+
+	report := s.Report()
+	for _, fn := range hooks {
+		s.appendErr(s.recovered(context.Background(), func(context.Context) error {
+			fn(report)
+			return nil
+		}))
+	}
+}
+
+// cleanup is a cleanup function, which runs at most once.
+type cleanup struct {
+	fn   func(context.Context) error
+	tier int
+	// timeout limits cleanup function within shutdown timeout, zero means no own limit.
+	timeout time.Duration
+	once    sync.Once
+
+	mtx    sync.Mutex
+	report CleanupReport
+}
+
+// addCleanup adds cleanup function of given tier, it returns
+// placeholder not added to squad if function is nil.
+func (s *Squad) addCleanup(tier int, name string, fn func(context.Context) error) *cleanup {
+	c := &cleanup{fn: fn, tier: tier, report: CleanupReport{Name: name}}
+	if fn != nil {
+		s.cancellationFuncs = append(s.cancellationFuncs, c)
+	}
+	return c
+}
+
+// run calls cleanup function bounded by ctx, subsequent calls do nothing.
+// Failed cleanup function is retried with exponential backoff
+// while ctx deadline allows, if backoff is positive.
+func (c *cleanup) run(ctx context.Context, backoff time.Duration, label labelFunc) (err error) {
+	c.once.Do(func() {
+		c.mtx.Lock()
+		c.report.Ran = true
+		c.mtx.Unlock()
+
+		if c.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+		}
+
+		policy := RetryPolicy{Backoff: Backoff{Initial: backoff}}
+		if backoff <= 0 {
+			policy.MaxAttempts = 1
+		}
+		start := time.Now()
+		err = Retry(ctx, policy, func(ctx context.Context) error {
+			return c.call(ctx, label)
+		})
+
+		c.mtx.Lock()
+		c.report.Err = err
+		c.report.Duration = time.Since(start)
+		c.mtx.Unlock()
+	})
+	return err
+}
+
+func (c *cleanup) call(ctx context.Context, label labelFunc) error {
+	c.mtx.Lock()
+	c.report.Attempts++
+	c.mtx.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-callTimeout(ctx, func(ctx context.Context) error {
+		return label(ctx, c.member(), phaseCleanup, c.fn)
+	}):
+		return err
+	}
+}
+
+func (c *cleanup) member() string {
+	if c.report.Name == "" {
+		return "cleanup"
+	}
+	return c.report.Name
+}
+
+func (c *cleanup) result() CleanupReport {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.report
+}
@@ -0,0 +1,113 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// GracefulListener is a listener, which stops accepting new connections when context
+// is done and tracks accepted connections until they are closed, so servers of any
+// protocol, e.g. raw TCP, get real connection draining by WaitIdle and CloseConns.
+// Accepted connections are wrapped, so they are not of concrete types like *net.TCPConn.
+type GracefulListener struct {
+	net.Listener
+
+	mtx   sync.Mutex
+	conns map[*trackedConn]struct{}
+	// idle is closed while there are no active connections.
+	idle chan struct{}
+}
+
+// NewGracefulListener returns listener, which stops accepting new connections
+// when ctx is done and tracks accepted connections, e.g. for listener returned
+// by Squad.Listen it is ctx of squad member.
+func NewGracefulListener(ctx context.Context, ln net.Listener) *GracefulListener {
+	return trackConns(newGracefulListener(ctx, ln, func() {}))
+}
+
+func trackConns(ln net.Listener) *GracefulListener {
+	idle := make(chan struct{})
+	close(idle)
+	return &GracefulListener{Listener: ln, conns: make(map[*trackedConn]struct{}), idle: idle}
+}
+
+func (l *GracefulListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &trackedConn{Conn: conn, l: l}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if len(l.conns) == 0 {
+		l.idle = make(chan struct{})
+	}
+	l.conns[c] = struct{}{}
+	return c, nil
+}
+
+// Active returns number of accepted connections, which have not been closed yet.
+func (l *GracefulListener) Active() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return len(l.conns)
+}
+
+// WaitIdle blocks until all accepted connections have been closed or ctx is done.
+func (l *GracefulListener) WaitIdle(ctx context.Context) error {
+	l.mtx.Lock()
+	idle := l.idle
+	l.mtx.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-idle:
+		return nil
+	}
+}
+
+// CloseConns closes forcibly accepted connections, which are still active.
+func (l *GracefulListener) CloseConns() error {
+	l.mtx.Lock()
+	conns := make([]*trackedConn, 0, len(l.conns))
+	for c := range l.conns {
+		conns = append(conns, c)
+	}
+	l.mtx.Unlock()
+
+	var err error
+	for _, c := range conns {
+		if closeErr := c.Close(); closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+			err = errors.Join(err, closeErr)
+		}
+	}
+	return err
+}
+
+func (l *GracefulListener) remove(c *trackedConn) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if _, ok := l.conns[c]; !ok {
+		return
+	}
+	delete(l.conns, c)
+	if len(l.conns) == 0 {
+		close(l.idle)
+	}
+}
+
+// trackedConn is a connection accepted by GracefulListener.
+type trackedConn struct {
+	net.Conn
+	l *GracefulListener
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.l.remove(c)
+	return err
+}
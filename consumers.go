@@ -1,8 +1,97 @@
 // helpers for run consumer workers.
 package squad
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
 
 // ConsumerLoop is interface for run graceful consumer, which take context different
 // context for consumer events/messages and handle them.
 type ConsumerLoop func(consumeContext, handleContext context.Context) error
+
+// DrainStrategy defines what consumer does with fetched but not yet handled
+// events/messages after it has been stopped, see WithDrainStrategy and Unhandled.
+type DrainStrategy interface {
+	drain(ctx context.Context, items any) error
+}
+
+// WithDrainStrategy sets drain strategy of consumer, see Squad.RunConsumer.
+// By default consumer stops fetching and finishes handling of all fetched
+// events/messages.
+func WithDrainStrategy(strategy DrainStrategy) TaskOption {
+	return func(t *task) {
+		t.drainStrategy = strategy
+	}
+}
+
+// Requeue returns drain strategy, which finishes handling of current event/message
+// and requeues rest of fetched ones one by one, e.g. by negative acknowledgement.
+func Requeue[T any](requeue func(ctx context.Context, item T) error) DrainStrategy {
+	return drainFunc[T](func(ctx context.Context, items []T) error {
+		for _, item := range items {
+			if err := requeue(ctx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// HandOff returns drain strategy, which finishes handling of current event/message
+// and hands off rest of fetched ones at once, e.g. to peer instance.
+func HandOff[T any](handOff func(ctx context.Context, items []T) error) DrainStrategy {
+	return drainFunc[T](handOff)
+}
+
+type drainFunc[T any] func(ctx context.Context, items []T) error
+
+func (fn drainFunc[T]) drain(ctx context.Context, items any) error {
+	typed, ok := items.([]T)
+	if !ok {
+		return &ItemTypeError{Items: reflect.TypeOf(items), Want: reflect.TypeOf(typed)}
+	}
+	return fn(ctx, typed)
+}
+
+// ItemTypeError is an error of Unhandled called with items of type,
+// which drain strategy of consumer has not been made for.
+type ItemTypeError struct {
+	// Items is type of passed items, Want is type of items of drain strategy.
+	Items, Want reflect.Type
+}
+
+func (e *ItemTypeError) Error() string {
+	return fmt.Sprintf("drain strategy expects %v, got %v", e.Want, e.Items)
+}
+
+// Unhandled passes fetched but not yet handled events/messages to drain strategy
+// of consumer, once consumer has been stopped. It reports true, if items have been
+// taken by strategy, so consumer must return without handling them.
+// Consumer loop calls it with handle context before handling each next item, e.g.
+//
+//	for i, msg := range batch {
+//		if taken, err := squad.Unhandled(handleCtx, batch[i:]); taken {
+//			return err
+//		}
+//		handle(handleCtx, msg)
+//	}
+//
+// Items must be of type, which strategy has been made for, otherwise they are
+// reported as taken with ItemTypeError, so consumer returns without handling them.
+func Unhandled[T any](handleCtx context.Context, items []T) (taken bool, err error) {
+	c, ok := handleCtx.Value(consumerKey{}).(*consumerDrain)
+	if !ok || c.strategy == nil || c.consumeCtx.Err() == nil || len(items) == 0 {
+		return false, nil
+	}
+	return true, c.strategy.drain(handleCtx, items)
+}
+
+// consumerKey is context key of consumer drain, which is carried by handle context.
+type consumerKey struct{}
+
+type consumerDrain struct {
+	consumeCtx context.Context
+	strategy   DrainStrategy
+}
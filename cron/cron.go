@@ -0,0 +1,165 @@
+// Package cron contains scheduler of squad members by cron expressions.
+package cron
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/moeryomenko/squad"
+)
+
+// Overlap is a policy of run, which is scheduled while previous run is in flight.
+type Overlap int
+
+const (
+	// Skip skips scheduled run.
+	Skip Overlap = iota + 1
+	// Queue runs scheduled run right after previous one, at most one run is queued.
+	Queue
+	// Concurrent runs scheduled run concurrently with previous one.
+	Concurrent
+)
+
+// Option is an option that can be applied to scheduler.
+type Option func(*scheduler)
+
+// WithOverlap sets policy of overlapping runs, by default it is Skip.
+func WithOverlap(policy Overlap) Option {
+	return func(s *scheduler) {
+		s.overlap = policy
+	}
+}
+
+// WithLocation sets time zone of cron expression, by default it is time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(s *scheduler) {
+		s.loc = loc
+	}
+}
+
+// WithTaskOptions sets options of squad member running scheduler.
+func WithTaskOptions(opts ...squad.TaskOption) Option {
+	return func(s *scheduler) {
+		s.taskOpts = opts
+	}
+}
+
+// Run runs job by cron expression as background member of squad. When squad begins
+// shutdown new runs are not scheduled, but in-flight runs keep going until squad
+// context is canceled. Failed run stops squad same as failure of member.
+func Run(s *squad.Squad, expr string, job func(context.Context) error, opts ...Option) error {
+	schedule, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+
+	sched := &scheduler{next: schedule.Next, job: job, overlap: Skip, loc: time.Local, failed: make(chan struct{})}
+	for _, opt := range opts {
+		opt(sched)
+	}
+
+	s.Run(func(ctx context.Context) error {
+		return sched.run(ctx, s.Draining())
+	}, append(slices.Clip(sched.taskOpts), squad.WithBackground())...)
+	return nil
+}
+
+// scheduler runs job at activations of schedule.
+type scheduler struct {
+	next     func(time.Time) time.Time
+	job      func(context.Context) error
+	overlap  Overlap
+	loc      *time.Location
+	taskOpts []squad.TaskOption
+
+	wg      sync.WaitGroup
+	mtx     sync.Mutex
+	busy    bool
+	queued  bool
+	stopped bool
+	err     error
+	failed  chan struct{}
+}
+
+func (s *scheduler) run(ctx context.Context, draining <-chan struct{}) error {
+	for {
+		next := s.next(time.Now().In(s.loc))
+		if next.IsZero() {
+			return s.stop()
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-draining:
+			timer.Stop()
+			return s.stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return s.stop()
+		case <-s.failed:
+			timer.Stop()
+			return s.stop()
+		case <-timer.C:
+			s.dispatch(ctx)
+		}
+	}
+}
+
+// dispatch starts run applying overlap policy.
+func (s *scheduler) dispatch(ctx context.Context) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.busy && s.overlap != Concurrent {
+		s.queued = s.overlap == Queue
+		return
+	}
+	s.busy = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for s.call(ctx) {
+		}
+	}()
+}
+
+// call runs job once, it reports whether queued run must follow.
+func (s *scheduler) call(ctx context.Context) bool {
+	if err := s.job(ctx); err != nil {
+		s.fail(err)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.queued && !s.stopped {
+		s.queued = false
+		return true
+	}
+	s.busy = false
+	return false
+}
+
+// fail records first failure of job and notifies scheduler.
+func (s *scheduler) fail(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.err == nil {
+		s.err = err
+		close(s.failed)
+	}
+}
+
+// stop stops scheduling and waits in-flight runs, it returns first failure of job.
+func (s *scheduler) stop() error {
+	s.mtx.Lock()
+	s.stopped = true
+	s.mtx.Unlock()
+	s.wg.Wait()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.err
+}
@@ -0,0 +1,79 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+func TestRun(t *testing.T) {
+	s, err := squad.New(squad.WithManualTrigger(squad.WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, Run(s, "* * *", func(context.Context) error { return nil }), ErrInvalidExpression)
+	assert.NoError(t, Run(s, "* * * * *", func(context.Context) error { return nil }))
+
+	s.Stop(nil)
+	assert.NoError(t, s.Wait())
+}
+
+func TestScheduler_Overlap(t *testing.T) {
+	testcases := map[Overlap]int32{Skip: 1, Queue: 2, Concurrent: 3}
+
+	for overlap, runs := range testcases {
+		overlap, runs := overlap, runs
+		t.Run(strconv.Itoa(int(overlap)), func(t *testing.T) {
+			var (
+				count   atomic.Int32
+				ticks   atomic.Int32
+				release = make(chan struct{})
+			)
+			sched := newTestScheduler(overlap, func(context.Context) error {
+				count.Add(1)
+				<-release
+				return nil
+			}, &ticks, 3)
+
+			draining := make(chan struct{})
+			done := make(chan error)
+			go func() { done <- sched.run(context.Background(), draining) }()
+
+			assert.Eventually(t, func() bool { return ticks.Load() > 3 }, time.Second, time.Millisecond)
+			close(release)
+			time.Sleep(20 * time.Millisecond)
+			close(draining)
+			assert.NoError(t, <-done)
+			assert.Equal(t, runs, count.Load())
+		})
+	}
+}
+
+func TestScheduler_Failure(t *testing.T) {
+	errJob := errors.New("failed job")
+	var ticks atomic.Int32
+	sched := newTestScheduler(Skip, func(context.Context) error { return errJob }, &ticks, 100)
+	assert.ErrorIs(t, sched.run(context.Background(), make(chan struct{})), errJob)
+}
+
+// newTestScheduler returns scheduler activating every millisecond given number of times,
+// ticks counts planned activations.
+func newTestScheduler(overlap Overlap, job func(context.Context) error, ticks *atomic.Int32, limit int32) *scheduler {
+	return &scheduler{
+		next: func(now time.Time) time.Time {
+			if ticks.Add(1) > limit {
+				return now.Add(time.Hour)
+			}
+			return now.Add(time.Millisecond)
+		},
+		job:     job,
+		overlap: overlap,
+		loc:     time.UTC,
+		failed:  make(chan struct{}),
+	}
+}
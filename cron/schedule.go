@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidExpression is returned by Parse for malformed cron expression.
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// maxSearchYears bounds search of next activation, e.g. for expression of February 30.
+const maxSearchYears = 5
+
+// Schedule is parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set when field starts with "*", so other day field alone restricts days.
+	domAny, dowAny bool
+}
+
+// field is bounds of cron expression field.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [...]field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Parse parses standard cron expression of five fields: minute, hour, day of month,
+// month and day of week. Each field is "*", value, range "a-b", list of them separated
+// by comma, optionally with step "/n". Sunday is both 0 and 7 day of week, if both
+// day fields are restricted, activation matches any of them.
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w: %q must have %d fields", ErrInvalidExpression, expr, len(fields))
+	}
+
+	var bits [len(fields)]uint64
+	for i, part := range parts {
+		var err error
+		if bits[i], err = fields[i].parse(part); err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidExpression, expr, err)
+		}
+	}
+	// NOTE: Sunday is both 0 and 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Schedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: strings.HasPrefix(parts[2], "*"), dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func (f field) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(expr, ",") {
+		from, to, step, err := f.parseItem(item)
+		if err != nil {
+			return 0, err
+		}
+		for value := from; value <= to; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// parseItem parses item of list into range with step.
+func (f field) parseItem(item string) (from, to, step int, err error) {
+	value, stepValue, hasStep := strings.Cut(item, "/")
+	step = 1
+	if hasStep {
+		if step, err = strconv.Atoi(stepValue); err != nil || step <= 0 {
+			return 0, 0, 0, fmt.Errorf("%s: invalid step %q", f.name, stepValue)
+		}
+	}
+
+	if value == "*" {
+		return f.min, f.max, step, nil
+	}
+	low, high, isRange := strings.Cut(value, "-")
+	if from, err = f.parseValue(low); err != nil {
+		return 0, 0, 0, err
+	}
+	to = from
+	switch {
+	case isRange:
+		if to, err = f.parseValue(high); err != nil {
+			return 0, 0, 0, err
+		}
+	case hasStep:
+		to = f.max
+	}
+	if from > to {
+		return 0, 0, 0, fmt.Errorf("%s: invalid range %q", f.name, value)
+	}
+	return from, to, step, nil
+}
+
+func (f field) parseValue(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: value %q out of range %d-%d", f.name, value, f.min, f.max)
+	}
+	return n, nil
+}
+
+// Next returns first activation after given time in its location,
+// it returns zero time if schedule never activates.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom, dow := s.dom&(1<<uint(t.Day())) != 0, s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,42 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	after := time.Date(2024, time.January, 31, 10, 7, 30, 0, time.UTC)
+
+	testcases := map[string]struct {
+		expr string
+		next time.Time
+	}{
+		"every minute":         {expr: "* * * * *", next: time.Date(2024, time.January, 31, 10, 8, 0, 0, time.UTC)},
+		"step":                 {expr: "*/5 * * * *", next: time.Date(2024, time.January, 31, 10, 10, 0, 0, time.UTC)},
+		"list and range":       {expr: "0,30 9-11 * * *", next: time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		"next day":             {expr: "0 9 * * *", next: time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		"leap day":             {expr: "0 0 29 2 *", next: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		"sunday as seven":      {expr: "0 0 * * 7", next: time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		"day of month or week": {expr: "0 0 15 * 1", next: time.Date(2024, time.February, 5, 0, 0, 0, 0, time.UTC)},
+		"never":                {expr: "0 0 30 2 *", next: time.Time{}},
+	}
+
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			schedule, err := Parse(tc.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.next, schedule.Next(after))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		assert.ErrorIs(t, err, ErrInvalidExpression, expr)
+	}
+}
@@ -0,0 +1,168 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/moeryomenko/synx"
+)
+
+// ErrInvalidDependencies is returned by New, when subsystems added by WithSubsystemDeps
+// have duplicate names, unknown or cyclic dependencies.
+var ErrInvalidDependencies = errors.New("invalid subsystem dependencies")
+
+// WithSubsystemDeps is Squad option that adds named subsystem, which depends on
+// other subsystems added by this option. Subsystems are initialized after bootstrap
+// stages in topological order and closed after other cleanup functions in reverse order,
+// independent subsystems are initialized and closed concurrently.
+// Subsystem is closed only if it has been initialized successfully.
+func WithSubsystemDeps(name string, deps []string, initFn, closeFn func(context.Context) error, opts ...SubsystemOpt) Option {
+	sub := &subsystem{}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	sub.name = name
+
+	return func(s *Squad) {
+		s.dependents = append(s.dependents, &dependent{
+			name:    name,
+			deps:    deps,
+			init:    sub.wrap(initFn),
+			cleanup: &cleanup{fn: sub.wrap(closeFn), tier: tierStorage, report: CleanupReport{Name: name}},
+		})
+		if sub.health != nil {
+			s.subsystems = append(s.subsystems, sub)
+		}
+	}
+}
+
+// dependent is a subsystem with dependencies.
+type dependent struct {
+	name        string
+	deps        []string
+	init        func(context.Context) error
+	cleanup     *cleanup
+	initialized atomic.Bool
+}
+
+// sortDependents returns subsystems in topological order.
+func sortDependents(nodes []*dependent) ([]*dependent, error) {
+	byName := make(map[string]*dependent, len(nodes))
+	for _, node := range nodes {
+		if _, ok := byName[node.name]; ok {
+			return nil, fmt.Errorf("%w: duplicate subsystem %s", ErrInvalidDependencies, node.name)
+		}
+		byName[node.name] = node
+	}
+
+	sorted := make([]*dependent, 0, len(nodes))
+	visiting, visited := make(map[string]bool), make(map[string]bool)
+	var visit func(node *dependent) error
+	visit = func(node *dependent) error {
+		switch {
+		case visited[node.name]:
+			return nil
+		case visiting[node.name]:
+			return fmt.Errorf("%w: cycle through subsystem %s", ErrInvalidDependencies, node.name)
+		}
+		visiting[node.name] = true
+
+		for _, name := range node.deps {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("%w: subsystem %s depends on unknown %s", ErrInvalidDependencies, node.name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		visited[node.name] = true
+		sorted = append(sorted, node)
+		return nil
+	}
+
+	for _, node := range nodes {
+		if err := visit(node); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// initDependents initializes subsystems as soon as their dependencies have been initialized,
+// subsystems initialized by previous attempt of bootstrap are skipped.
+func (s *Squad) initDependents(ctx context.Context) error {
+	ready := make(map[string]chan struct{}, len(s.dependents))
+	for _, node := range s.dependents {
+		ready[node.name] = make(chan struct{})
+	}
+
+	group := synx.NewErrGroup(ctx)
+	for _, node := range s.dependents {
+		node := node
+		group.Go(func(ctx context.Context) error {
+			for _, name := range node.deps {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-ready[name]:
+				}
+			}
+
+			if !node.initialized.Load() && node.init != nil {
+				if err := s.labeled(ctx, node.name, phaseBootstrap, node.init); err != nil {
+					return err
+				}
+			}
+			node.initialized.Store(true)
+			close(ready[node.name])
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+// closeDependents closes initialized subsystems after all subsystems depending on them
+// have been closed, failure of subsystem does not prevent closing its dependencies.
+func (s *Squad) closeDependents(ctx context.Context) error {
+	closed := make(map[string]chan struct{}, len(s.dependents))
+	users := make(map[string][]string, len(s.dependents))
+	for _, node := range s.dependents {
+		closed[node.name] = make(chan struct{})
+		for _, name := range node.deps {
+			users[name] = append(users[name], node.name)
+		}
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		errs []error
+	)
+	for _, node := range s.dependents {
+		node := node
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(closed[node.name])
+
+			for _, name := range users[node.name] {
+				<-closed[name]
+			}
+			if !node.initialized.Load() || node.cleanup.fn == nil {
+				return
+			}
+
+			err := s.runCleanup(ctx, node.cleanup)
+			mtx.Lock()
+			errs = append(errs, err)
+			mtx.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return joinErrors(errs...)
+}
@@ -0,0 +1,111 @@
+package squad
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// DrainTimer is a timer of graceful period, after which squad context is canceled.
+// Its delay can be extended, e.g. for large in-flight batch, or cut short at runtime.
+type DrainTimer struct {
+	s *Squad
+	// deadline is planned cancellation of squad context, zero until drain begins.
+	deadline atomic.Int64
+	changed  chan struct{}
+}
+
+// DrainTimer returns drain timer of squad.
+func (s *Squad) DrainTimer() *DrainTimer {
+	return &s.drainTimer
+}
+
+// DrainTimerFrom returns drain timer of squad carried by ctx of member
+// or request, see InjectShutdownState, ok is false if ctx does not carry squad.
+func DrainTimerFrom(ctx context.Context) (timer *DrainTimer, ok bool) {
+	s, ok := ctx.Value(squadKey{}).(*Squad)
+	if !ok {
+		return nil, false
+	}
+	return s.DrainTimer(), true
+}
+
+// Extend postpones cancellation of squad context by given duration, but no later than
+// hard deadline, extension is taken from shutdown timeout of cleanup functions.
+// It returns time left until cancellation, extension before drain has no effect.
+func (t *DrainTimer) Extend(d time.Duration) time.Duration {
+	for {
+		deadline := t.deadline.Load()
+		if deadline == 0 {
+			return 0
+		}
+
+		hard := t.s.hardDeadline().UnixNano()
+		next := max(min(deadline+int64(d), hard), deadline)
+		if t.deadline.CompareAndSwap(deadline, next) {
+			t.notify()
+			return max(time.Until(time.Unix(0, next)), 0)
+		}
+	}
+}
+
+// Finish cuts drain short, so squad context is canceled immediately.
+func (t *DrainTimer) Finish() {
+	for {
+		deadline := t.deadline.Load()
+		if deadline == 0 || t.deadline.CompareAndSwap(deadline, time.Now().UnixNano()) {
+			break
+		}
+	}
+	t.notify()
+}
+
+// Remaining returns time left until cancellation of squad context, zero unless squad is draining.
+func (t *DrainTimer) Remaining() time.Duration {
+	deadline := t.deadline.Load()
+	if deadline == 0 {
+		return 0
+	}
+	return max(time.Until(time.Unix(0, deadline)), 0)
+}
+
+// start plans cancellation of squad context after given delay.
+func (t *DrainTimer) start(delay time.Duration) {
+	t.deadline.CompareAndSwap(0, time.Now().Add(max(delay, 0)).UnixNano())
+	t.notify()
+}
+
+func (t *DrainTimer) notify() {
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until planned cancellation, it reports false if ctx is done before.
+func (t *DrainTimer) wait(ctx context.Context) bool {
+	for {
+		timer := time.NewTimer(time.Until(time.Unix(0, t.deadline.Load())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-t.changed:
+			timer.Stop()
+		case <-timer.C:
+			if time.Now().UnixNano() >= t.deadline.Load() {
+				return true
+			}
+		}
+	}
+}
+
+// limit returns deadline of cleanup functions bounded by hard deadline,
+// e.g. if drain has been extended.
+func (t *DrainTimer) limit(deadline time.Time) time.Time {
+	hard := t.s.hardDeadline()
+	if deadline.After(hard) {
+		return hard
+	}
+	return deadline
+}
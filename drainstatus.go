@@ -0,0 +1,91 @@
+package squad
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// DrainPhase is a phase of shutdown of subsystem published for external probes and humans,
+// besides predefined phases subsystems may publish own ones, e.g. "committing offsets".
+type DrainPhase string
+
+const (
+	// DrainDrained means subsystem has stopped accepting work, e.g. server has been drained.
+	DrainDrained DrainPhase = "drained"
+	// DrainClosing means cleanup function of subsystem is running.
+	DrainClosing DrainPhase = "closing"
+	// DrainClosed means cleanup function of subsystem has succeeded.
+	DrainClosed DrainPhase = "closed"
+	// DrainFailed means cleanup function of subsystem has failed.
+	DrainFailed DrainPhase = "failed"
+)
+
+// PublishDrainPhase publishes actual drain phase of named subsystem, see DrainStatus.
+// Phases of subsystems added by WithSubsystem and WithSubsystemDeps are published
+// automatically around their cleanup functions.
+func (s *Squad) PublishDrainPhase(name string, phase DrainPhase) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.drainPhases == nil {
+		s.drainPhases = make(map[string]DrainPhase)
+	}
+	s.drainPhases[name] = phase
+}
+
+// PublishDrainPhase publishes drain phase on behalf of subsystem, which cleanup function
+// has received ctx, it does nothing for other contexts.
+func PublishDrainPhase(ctx context.Context, phase DrainPhase) {
+	if p, ok := ctx.Value(drainPhaseKey{}).(drainPublisher); ok {
+		p.s.PublishDrainPhase(p.name, phase)
+	}
+}
+
+// DrainStatus returns published drain phases of subsystems by their names,
+// it is safe to call while squad is running.
+func (s *Squad) DrainStatus() map[string]DrainPhase {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return maps.Clone(s.drainPhases)
+}
+
+// drainPhaseKey is context key of publisher of subsystem drain phases.
+type drainPhaseKey struct{}
+
+type drainPublisher struct {
+	s    *Squad
+	name string
+}
+
+// trackDrainPhases publishes drain phases of named cleanup function around call of run.
+func (s *Squad) trackDrainPhases(ctx context.Context, c *cleanup, run func(context.Context) error) error {
+	if c.report.Name == "" {
+		return run(ctx)
+	}
+
+	s.PublishDrainPhase(c.report.Name, DrainClosing)
+	err := run(context.WithValue(ctx, drainPhaseKey{}, drainPublisher{s: s, name: c.report.Name}))
+	if err != nil {
+		s.PublishDrainPhase(c.report.Name, DrainFailed)
+	} else {
+		s.PublishDrainPhase(c.report.Name, DrainClosed)
+	}
+	return err
+}
+
+// serveDrainStatus responds drain phases of subsystems as text lines "name: phase".
+func (s *Squad) serveDrainStatus(w http.ResponseWriter, _ *http.Request) {
+	status := s.DrainStatus()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	names := make([]string, 0, len(status))
+	for name := range status {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s: %s\n", name, status[name])
+	}
+}
@@ -0,0 +1,105 @@
+package squad
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// ListenDualStack announces on the local address by independent tcp4 and tcp6
+// listeners managed as one listener, for environments where binding "::" alone
+// is not acceptable. Failure of any family is reported with its network and closes
+// other one. If port is zero, tcp6 listener binds port chosen for tcp4 one.
+// Listener is graceful and closed by squad same as one returned by Squad.Listen.
+func (s *Squad) ListenDualStack(address string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ln4, err := net.Listen("tcp4", address)
+	if err != nil {
+		return nil, fmt.Errorf("listen tcp4: %w", err)
+	}
+	if port == "0" {
+		address = net.JoinHostPort(host, strconv.Itoa(ln4.Addr().(*net.TCPAddr).Port))
+	}
+	ln6, err := net.Listen("tcp6", address)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("listen tcp6: %w", err), ln4.Close())
+	}
+
+	return s.trackListener(newMultiListener(ln4, ln6)), nil
+}
+
+// multiListener accepts connections of several listeners.
+type multiListener struct {
+	listeners []net.Listener
+	accepted  chan accepted
+	closed    chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+type accepted struct {
+	conn net.Conn
+	err  error
+}
+
+func newMultiListener(listeners ...net.Listener) *multiListener {
+	m := &multiListener{
+		listeners: listeners,
+		accepted:  make(chan accepted),
+		closed:    make(chan struct{}),
+	}
+	for _, ln := range listeners {
+		go m.serve(ln)
+	}
+	return m
+}
+
+// serve passes connections of listener to Accept until listener is closed.
+// Errors are passed as is, so temporary net.Error, e.g. EMFILE, is retried
+// by server instead of stopping it.
+func (m *multiListener) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+
+		select {
+		case m.accepted <- accepted{conn: conn, err: err}:
+		case <-m.closed:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+	}
+}
+
+func (m *multiListener) Accept() (net.Conn, error) {
+	select {
+	case a := <-m.accepted:
+		return a.conn, a.err
+	case <-m.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes all listeners.
+func (m *multiListener) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		for _, ln := range m.listeners {
+			m.closeErr = errors.Join(m.closeErr, ln.Close())
+		}
+	})
+	return m.closeErr
+}
+
+// Addr returns address of first listener.
+func (m *multiListener) Addr() net.Addr {
+	return m.listeners[0].Addr()
+}
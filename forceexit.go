@@ -0,0 +1,79 @@
+package squad
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"runtime/pprof"
+	"time"
+)
+
+// WithForceExitAfter is a Squad option that forcibly exits process with status 1,
+// if squad has not stopped in given duration after hard deadline, e.g. cleanup function
+// or hook is blocked in uncancellable call. Stacks of all goroutines are dumped to stderr
+// before exit, so hang is diagnosed instead of being killed silently by SIGKILL.
+func WithForceExitAfter(after time.Duration) Option {
+	return func(s *Squad) {
+		s.forceExit.after = after
+	}
+}
+
+// forceExit is configuration and timer of forced exit, see WithForceExitAfter.
+type forceExit struct {
+	after time.Duration
+	// created and timer are guarded by mtx of lifecycle, timer is armed only after New
+	// has succeeded, so process handling failure of New is not killed.
+	created bool
+	timer   *time.Timer
+	// exit and output are replaced by tests.
+	exit   func(int)
+	output io.Writer
+}
+
+// created marks squad returned by New and arms timer of forced exit,
+// if squad has begun shutdown during New.
+func (s *Squad) created() {
+	s.lifecycle.mtx.Lock()
+	s.forceExit.created = true
+	s.lifecycle.mtx.Unlock()
+	s.armForceExit()
+}
+
+// armForceExit starts timer of forced exit once squad begins shutdown,
+// which fires after given duration since hard deadline.
+func (s *Squad) armForceExit() {
+	if s.forceExit.after <= 0 {
+		return
+	}
+
+	s.lifecycle.mtx.Lock()
+	defer s.lifecycle.mtx.Unlock()
+	if !s.forceExit.created || s.forceExit.timer != nil ||
+		s.lifecycle.state < StateDraining || s.lifecycle.state == StateStopped {
+		return
+	}
+
+	// NOTE: deadline is counted since beginning of shutdown same as deadlines of drain and cleanup functions.
+	deadline := s.hardDeadline().Add(s.forceExit.after)
+	s.forceExit.timer = time.AfterFunc(time.Until(deadline), func() {
+		s.log(slog.LevelError, "squad did not stop in time, forced exit", "deadline", deadline)
+		output, exit := s.forceExit.output, s.forceExit.exit
+		if output == nil {
+			output = os.Stderr
+		}
+		if exit == nil {
+			exit = os.Exit
+		}
+		_ = pprof.Lookup("goroutine").WriteTo(output, 2)
+		exit(1)
+	})
+}
+
+// disarmForceExit stops timer of forced exit after squad has stopped or New has failed.
+func (s *Squad) disarmForceExit() {
+	s.lifecycle.mtx.Lock()
+	defer s.lifecycle.mtx.Unlock()
+	if s.forceExit.timer != nil {
+		s.forceExit.timer.Stop()
+	}
+}
@@ -0,0 +1,53 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// GRPCServer is a gRPC server, e.g. grpc.Server.
+type GRPCServer interface {
+	Serve(net.Listener) error
+	// GracefulStop stops accepting connections and waits active RPCs.
+	GracefulStop()
+	Stop()
+}
+
+// RunGRPCServer is wrapper function for launch gRPC server on given listener,
+// when squad begins shutdown server is gracefully stopped with given lifecycle
+// configuration, server is stopped forcibly if streams are not drained in shutdown timeout.
+// Listener is closed as soon as server exits, at the latest before cleanup functions.
+func (s *Squad) RunGRPCServer(srv GRPCServer, ln net.Listener, opts ServerOptions) {
+	t := s.newTask([]TaskOption{WithTaskName("grpc server " + ln.Addr().String())})
+	s.addListener(ln)
+
+	s.runShutdowner(t, func(context.Context) error {
+		return errors.Join(srv.Serve(ln), s.closeListener(ln))
+	}, grpcShutdowner{srv}, opts)
+}
+
+// grpcShutdowner adapts gRPC server to Shutdowner.
+type grpcShutdowner struct {
+	srv GRPCServer
+}
+
+func (g grpcShutdowner) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		g.srv.GracefulStop()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-stopped:
+		return nil
+	}
+}
+
+func (g grpcShutdowner) Close() error {
+	g.srv.Stop()
+	return nil
+}
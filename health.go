@@ -0,0 +1,65 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrShuttingDown is returned when squad has begun shutdown.
+var ErrShuttingDown = errors.New("squad is shutting down")
+
+// Ready reports whether squad is ready to serve: squad is not shutting down,
+// bootstrap and warmup have been completed and health checks of all subsystems are passed.
+func (s *Squad) Ready(ctx context.Context) error {
+	if s.unready.Load() || s.drainContext().Err() != nil {
+		return ErrShuttingDown
+	}
+	if err := s.bindFailed(); err != nil {
+		return err
+	}
+	if err := s.bootstrapReady(); err != nil {
+		return err
+	}
+	if err := s.warmedUp(ctx); err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	subsystems := slices.Clone(s.subsystems)
+	s.mtx.Unlock()
+
+	var err error
+	for _, sub := range subsystems {
+		err = errors.Join(err, sub.wrap(sub.health)(ctx))
+	}
+	return err
+}
+
+// addHealthCheck adds health check of running squad member to Squad.Ready.
+func (s *Squad) addHealthCheck(sub *subsystem) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.subsystems = append(s.subsystems, sub)
+}
+
+// subsystem is metadata of subsystem added to squad.
+type subsystem struct {
+	name   string
+	health func(context.Context) error
+}
+
+// wrap labels errors of given subsystem function by subsystem name.
+func (s *subsystem) wrap(fn func(context.Context) error) func(context.Context) error {
+	if s.name == "" || fn == nil {
+		return fn
+	}
+
+	return func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return fmt.Errorf("subsystem %s: %w", s.name, err)
+		}
+		return nil
+	}
+}
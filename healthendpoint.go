@@ -0,0 +1,92 @@
+package squad
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// HealthEndpointOpt is an option that can be applied to health endpoint.
+type HealthEndpointOpt func(*healthEndpoint)
+
+// WithHealthPaths sets paths of liveness and readiness probes, by default /live and /ready.
+func WithHealthPaths(live, ready string) HealthEndpointOpt {
+	return func(h *healthEndpoint) {
+		h.livePath, h.readyPath = live, ready
+	}
+}
+
+// WithDrainStatusPath sets path of drain status of subsystems, by default /drain.
+func WithDrainStatusPath(path string) HealthEndpointOpt {
+	return func(h *healthEndpoint) {
+		h.drainPath = path
+	}
+}
+
+// WithMembersPath sets path of status of squad members, by default /members.
+func WithMembersPath(path string) HealthEndpointOpt {
+	return func(h *healthEndpoint) {
+		h.membersPath = path
+	}
+}
+
+// WithHealthEndpoint is a Squad option that starts http server on given address
+// exposing liveness and readiness probes. Readiness probe reports Squad.Ready,
+// so it flips to 503 as soon as squad begins shutdown, and liveness probe
+// keeps responding 200 until cleanup functions have been completed.
+// Drain status responds published drain phases of subsystems, see Squad.DrainStatus,
+// and members status responds liveness, restarts and goroutines of members, see Squad.Describe.
+func WithHealthEndpoint(addr string, opts ...HealthEndpointOpt) Option {
+	endpoint := &healthEndpoint{addr: addr, livePath: "/live", readyPath: "/ready", drainPath: "/drain", membersPath: "/members"}
+	for _, opt := range opts {
+		opt(endpoint)
+	}
+
+	return func(s *Squad) {
+		s.healthEndpoint = endpoint
+	}
+}
+
+type healthEndpoint struct {
+	addr                string
+	livePath, readyPath string
+	drainPath           string
+	membersPath         string
+	srv                 *http.Server
+	// ln is closed along with server, since server may not be serving it yet.
+	ln net.Listener
+}
+
+// serveHealth starts health endpoint, which is not squad member,
+// so it outlives members and is closed after shutdown, see closeHealth.
+func (s *Squad) serveHealth(h *healthEndpoint) error {
+	ln, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(h.livePath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(h.readyPath, func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Ready(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(h.drainPath, s.serveDrainStatus)
+	mux.HandleFunc(h.membersPath, s.serveMembers)
+
+	h.srv, h.ln = &http.Server{Handler: mux, ReadHeaderTimeout: time.Second}, ln
+	go func() { _ = h.srv.Serve(ln) }()
+	return nil
+}
+
+func (s *Squad) closeHealth() {
+	if s.healthEndpoint != nil && s.healthEndpoint.srv != nil {
+		_ = s.healthEndpoint.srv.Close()
+		_ = s.healthEndpoint.ln.Close()
+	}
+}
@@ -0,0 +1,89 @@
+package squad
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks are callbacks on edges of squad lifecycle, e.g. for deregistration from service
+// discovery or notification of supervisor. OnStarted and OnShutdownDone are called
+// synchronously, so they delay the edge.
+type Hooks struct {
+	// OnStarted is called after bootstrap has been completed.
+	OnStarted func(context.Context)
+	// OnShutdownStart is called once in background when running squad begins shutdown,
+	// so it does not delay graceful period or handling of signals. It runs concurrently
+	// with drain and cleanup functions, its context is done at hard deadline of squad,
+	// and Wait waits it no longer than hard deadline. It is not called, if bootstrap
+	// has not been completed, e.g. New has failed.
+	OnShutdownStart func(context.Context)
+	// OnShutdownDone is called after cleanup functions, before Wait returns.
+	OnShutdownDone func(context.Context)
+}
+
+// WithHooks is a Squad option that adds lifecycle hooks, hooks of the same
+// edge are called in order of adding, nil hooks are skipped.
+func WithHooks(hooks Hooks) Option {
+	return func(s *Squad) {
+		s.hooks = append(s.hooks, hooks)
+	}
+}
+
+// runHooks calls hooks of lifecycle edges crossed by given state change.
+func (s *Squad) runHooks(change StateChange) {
+	if change.To == StateRunning {
+		s.callHooks(func(h Hooks) func(context.Context) { return h.OnStarted })
+	}
+	if change.From < StateDraining && change.To >= StateDraining {
+		// NOTE: squad, which has not started, e.g. New of which has failed, has nothing to shut down.
+		if change.From == StateRunning {
+			s.startShutdownHooks()
+		} else {
+			close(s.hooksDone)
+		}
+	}
+	if change.To == StateStopped {
+		s.callHooks(func(h Hooks) func(context.Context) { return h.OnShutdownDone })
+	}
+}
+
+func (s *Squad) callHooks(edge func(Hooks) func(context.Context)) {
+	s.callHooksContext(s.hookContext(), edge)
+}
+
+func (s *Squad) callHooksContext(ctx context.Context, edge func(Hooks) func(context.Context)) {
+	for _, hooks := range s.hooks {
+		if hook := edge(hooks); hook != nil {
+			hook(ctx)
+		}
+	}
+}
+
+// startShutdownHooks calls OnShutdownStart hooks in background bounded by hard deadline,
+// so time consumed by them is charged to graceful period and shutdown timeout.
+func (s *Squad) startShutdownHooks() {
+	go func() {
+		defer close(s.hooksDone)
+
+		ctx, cancel := context.WithDeadline(s.hookContext(), s.hardDeadline())
+		defer cancel()
+		s.callHooksContext(ctx, func(h Hooks) func(context.Context) { return h.OnShutdownStart })
+	}()
+}
+
+// waitShutdownHooks waits OnShutdownStart hooks no longer than hard deadline.
+func (s *Squad) waitShutdownHooks() {
+	timer := time.NewTimer(time.Until(s.hardDeadline()))
+	defer timer.Stop()
+
+	select {
+	case <-s.hooksDone:
+	case <-timer.C:
+	}
+}
+
+// hookContext returns context of lifecycle hooks, which carries squad,
+// but is not canceled with squad context.
+func (s *Squad) hookContext() context.Context {
+	return context.WithoutCancel(s.ctx)
+}
@@ -0,0 +1,63 @@
+package squad
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// hostLockPoll is interval of attempts to acquire host lock.
+const hostLockPoll = 10 * time.Millisecond
+
+// WithHostLock is a Squad option that serializes shutdown of processes on one host,
+// which share resource, e.g. embedded database or cache file: cleanup functions
+// and shutdown phases run only while holding exclusive lock of file at path.
+// Lock is awaited within shutdown timeout, if it is not acquired in time,
+// cleanup functions run anyway and failure is reported as squad error.
+func WithHostLock(path string) Option {
+	return func(s *Squad) {
+		s.hostLock = path
+	}
+}
+
+// withHostLock runs fn holding host lock, if it is set.
+func (s *Squad) withHostLock(fn func() error) error {
+	if s.hostLock == "" {
+		return fn()
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), s.cleanupDeadline())
+	defer cancel()
+
+	unlock, err := lockHost(ctx, s.hostLock)
+	if err != nil {
+		s.log(slog.LevelWarn, "host lock is not acquired", "path", s.hostLock, "error", err)
+		return joinErrors(fmt.Errorf("host lock %s: %w", s.hostLock, err), fn())
+	}
+
+	err = fn()
+	if unlockErr := unlock(); unlockErr != nil {
+		err = joinErrors(err, fmt.Errorf("host lock %s: %w", s.hostLock, unlockErr))
+	}
+	return err
+}
+
+// acquire polls try until it acquires lock or ctx is done.
+func acquire(ctx context.Context, try func() (bool, error)) error {
+	ticker := time.NewTicker(hostLockPoll)
+	defer ticker.Stop()
+
+	for {
+		ok, err := try()
+		if ok || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
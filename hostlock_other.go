@@ -0,0 +1,30 @@
+//go:build !unix
+
+package squad
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// lockHost acquires lock by exclusive creation of file at path within ctx,
+// it returns function releasing lock. File of crashed process must be removed
+// manually, since there is no flock on this platform.
+func lockHost(ctx context.Context, path string) (func() error, error) {
+	var f *os.File
+	err := acquire(ctx, func() (ok bool, err error) {
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return errors.Join(f.Close(), os.Remove(path))
+	}, nil
+}
@@ -0,0 +1,43 @@
+package squad
+
+import (
+	"context"
+	"net/http"
+)
+
+// HTTP3Server is a HTTP/3 server, e.g. http3.Server of quic-go.
+type HTTP3Server interface {
+	ListenAndServe() error
+	// Shutdown sends GOAWAY to clients and waits active streams.
+	Shutdown(context.Context) error
+	Close() error
+	// SetQUICHeaders sets Alt-Svc header advertising HTTP/3.
+	SetQUICHeaders(http.Header) error
+}
+
+// RunHTTP3Server is wrapper function for launch HTTP/3 server paired with TCP server,
+// which advertises HTTP/3 by Alt-Svc header until squad begins shutdown, so new clients
+// stay on TCP during drain. Both servers are shut down with given lifecycle configuration,
+// HTTP/3 server is closed if its active streams are not drained in shutdown timeout.
+// Paired TCP server is optional.
+func (s *Squad) RunHTTP3Server(h3 HTTP3Server, tcp *http.Server, opts ServerOptions) {
+	if tcp != nil {
+		ctx, handler := s.drainContext(), tcp.Handler
+		if handler == nil {
+			handler = http.DefaultServeMux
+		}
+
+		tcp.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ctx.Err() == nil {
+				_ = h3.SetQUICHeaders(w.Header())
+			}
+			handler.ServeHTTP(w, r)
+		})
+		s.RunServerWithOptions(tcp, opts)
+	}
+
+	t := s.newTask([]TaskOption{WithTaskName("http3 server")})
+	s.runShutdowner(t, func(context.Context) error {
+		return h3.ListenAndServe()
+	}, h3, opts)
+}
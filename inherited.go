@@ -0,0 +1,101 @@
+package squad
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// listenFDsStart is first file descriptor passed by socket activation.
+const listenFDsStart = 3
+
+// WithInheritedListeners is a Squad option that takes listening sockets passed by
+// systemd socket activation, i.e. by LISTEN_PID and LISTEN_FDS. Squad.Listen and so
+// RunServer return inherited listener bound to requested address instead of binding new
+// one, e.g. gRPC server gets it by Squad.Listen too. So socket-activated service keeps
+// listening socket across restarts, pending connections are accepted by next instance.
+// Environment variables are unset, so they are not inherited by child processes.
+func WithInheritedListeners() Option {
+	return func(s *Squad) {
+		s.inheritSockets = true
+	}
+}
+
+func (s *Squad) inheritListeners() error {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(key)
+	}
+	if fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil {
+		return fmt.Errorf("invalid LISTEN_FDS: %w", err)
+	}
+	return s.inheritFDs(listenFDsStart, n)
+}
+
+// inheritFDs takes n listening sockets starting from given file descriptor.
+func (s *Squad) inheritFDs(start, n int) error {
+	listeners := make([]net.Listener, 0, n)
+	for fd := start; fd < start+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		// NOTE: listener holds duplicate of file descriptor.
+		ln, err := net.FileListener(f)
+		err = errors.Join(err, f.Close())
+		if err != nil {
+			for _, ln := range listeners {
+				_ = ln.Close()
+			}
+			return fmt.Errorf("inherited listener %d: %w", fd, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	for _, ln := range listeners {
+		ln = s.trackListener(ln)
+		s.mtx.Lock()
+		s.inherited = append(s.inherited, ln)
+		s.mtx.Unlock()
+	}
+	return nil
+}
+
+// inheritedListener takes inherited listener bound to given address, if any.
+func (s *Squad) inheritedListener(network, address string) net.Listener {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for i, ln := range s.inherited {
+		if listensOn(ln.Addr(), network, address) {
+			s.inherited = slices.Delete(s.inherited, i, i+1)
+			return ln
+		}
+	}
+	return nil
+}
+
+// listensOn reports whether listener bound to addr serves given address,
+// unspecified host matches listener bound to any address, e.g. ":8080" and "[::]:8080".
+func listensOn(addr net.Addr, network, address string) bool {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		want, err := net.ResolveTCPAddr(network, address)
+		if err != nil || want.Port != addr.Port {
+			return false
+		}
+		if want.IP == nil || want.IP.IsUnspecified() {
+			return addr.IP == nil || addr.IP.IsUnspecified()
+		}
+		return want.IP.Equal(addr.IP)
+	case *net.UnixAddr:
+		return addr.Net == network && addr.Name == address
+	default:
+		return false
+	}
+}
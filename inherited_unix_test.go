@@ -0,0 +1,55 @@
+//go:build unix
+
+package squad
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSquad_InheritedListeners(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	f, err := ln.(*net.TCPListener).File()
+	assert.NoError(t, err)
+	// NOTE: socket stays bound by duplicate of file descriptor, as if passed by systemd,
+	// ownership of which is taken by squad.
+	fd, err := syscall.Dup(int(f.Fd()))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	assert.NoError(t, ln.Close())
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(time.Second)))
+	assert.NoError(t, err)
+	assert.NoError(t, testGroup.inheritFDs(fd, 1))
+
+	_, err = testGroup.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	testGroup.RunServer(&http.Server{
+		Addr:              addr,
+		Handler:           http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { _, _ = io.WriteString(w, "inherited") }),
+		ReadHeaderTimeout: time.Second,
+	})
+
+	resp, err := http.Get("http://" + addr)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, "inherited", string(body))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+
+	assert.True(t, listensOn(&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", ":8080"))
+	assert.False(t, listensOn(&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", ":8081"))
+	assert.False(t, listensOn(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, "tcp", "127.0.0.2:8080"))
+	assert.True(t, listensOn(&net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "unix", "/run/app.sock"))
+}
@@ -0,0 +1,56 @@
+// Package kafka contains adapter of Kafka consumer groups to squad consumers,
+// e.g. sarama.ConsumerGroup, without dependency on Kafka client.
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	"github.com/moeryomenko/squad"
+)
+
+// ConsumerGroup is a Kafka consumer group, e.g. sarama.ConsumerGroup,
+// which is ConsumerGroup[sarama.ConsumerGroupHandler].
+type ConsumerGroup[H any] interface {
+	// Consume joins consumer group and consumes topics by handler until
+	// rebalance or cancellation of ctx.
+	Consume(ctx context.Context, topics []string, handler H) error
+	// Close leaves consumer group and commits marked offsets.
+	Close() error
+}
+
+// handleKey is context key of handle context.
+type handleKey struct{}
+
+// RunConsumerGroup runs consumer group as squad consumer, e.g.
+//
+//	kafka.RunConsumerGroup[sarama.ConsumerGroupHandler](s, cg, topics, handler)
+//
+// When squad begins shutdown consumer group stops fetching messages, in-flight messages
+// are handled within HandleContext and marked offsets are committed by closing
+// consumer group before consumer returns.
+func RunConsumerGroup[H any](s *squad.Squad, cg ConsumerGroup[H], topics []string, handler H, opts ...squad.TaskOption) {
+	s.RunConsumer(func(consumeCtx, handleCtx context.Context) error {
+		ctx := context.WithValue(consumeCtx, handleKey{}, handleCtx)
+
+		var err error
+		for err == nil && consumeCtx.Err() == nil {
+			err = cg.Consume(ctx, topics, handler)
+		}
+		if consumeCtx.Err() != nil {
+			err = nil
+		}
+		return errors.Join(err, cg.Close())
+	}, opts...)
+}
+
+// HandleContext returns context for handling of message, which is not canceled when
+// consumer stops fetching, given ctx is a context of consumer group session, e.g.
+// sarama.ConsumerGroupSession.Context(). It returns ctx if it is not context of
+// consumer run by RunConsumerGroup.
+func HandleContext(ctx context.Context) context.Context {
+	if handleCtx, ok := ctx.Value(handleKey{}).(context.Context); ok {
+		return handleCtx
+	}
+	return ctx
+}
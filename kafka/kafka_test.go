@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+// testHandler handles messages of test consumer group.
+type testHandler func(ctx context.Context, message string)
+
+type testConsumerGroup struct {
+	messages chan string
+	sessions atomic.Int32
+	closed   atomic.Bool
+	err      error
+}
+
+func (cg *testConsumerGroup) Consume(ctx context.Context, _ []string, handler testHandler) error {
+	// NOTE: session ends by rebalance after every message.
+	cg.sessions.Add(1)
+	select {
+	case <-ctx.Done():
+		return nil
+	case message := <-cg.messages:
+		handler(ctx, message)
+		return cg.err
+	}
+}
+
+func (cg *testConsumerGroup) Close() error {
+	cg.closed.Store(true)
+	return nil
+}
+
+func TestRunConsumerGroup(t *testing.T) {
+	s, err := squad.New(squad.WithManualTrigger(squad.WithShutdownInGracePriod(time.Second)))
+	assert.NoError(t, err)
+
+	cg := &testConsumerGroup{messages: make(chan string)}
+	handling, handled := make(chan struct{}), make(chan string, 2)
+	RunConsumerGroup[testHandler](s, cg, []string{"events"}, func(ctx context.Context, message string) {
+		if message == "slow" {
+			close(handling)
+			<-ctx.Done()
+			// NOTE: handle context outlives consumer session.
+			assert.NoError(t, HandleContext(ctx).Err())
+		}
+		handled <- message
+	})
+
+	cg.messages <- "fast"
+	cg.messages <- "slow"
+	<-handling
+	s.Stop(nil)
+	assert.NoError(t, s.Wait())
+
+	assert.Equal(t, "fast", <-handled)
+	assert.Equal(t, "slow", <-handled)
+	assert.True(t, cg.closed.Load())
+	assert.GreaterOrEqual(t, cg.sessions.Load(), int32(2))
+}
+
+func TestRunConsumerGroup_Failure(t *testing.T) {
+	errBroker := errors.New("broker is unavailable")
+
+	s, err := squad.New()
+	assert.NoError(t, err)
+	cg := &testConsumerGroup{messages: make(chan string, 1), err: errBroker}
+	cg.messages <- "message"
+	RunConsumerGroup[testHandler](s, cg, nil, func(context.Context, string) {})
+
+	assert.ErrorIs(t, s.Wait(), errBroker)
+	assert.True(t, cg.closed.Load())
+}
@@ -0,0 +1,54 @@
+// Package kafkago contains adapter of segmentio/kafka-go readers to squad consumers,
+// without dependency on kafka-go.
+package kafkago
+
+import (
+	"context"
+	"errors"
+
+	"github.com/moeryomenko/squad"
+)
+
+// Reader is a reader of Kafka messages, e.g. *kafka.Reader,
+// which is Reader[kafka.Message].
+type Reader[M any] interface {
+	// FetchMessage reads next message without committing its offset.
+	FetchMessage(ctx context.Context) (M, error)
+	// CommitMessages commits offsets of given messages.
+	CommitMessages(ctx context.Context, msgs ...M) error
+	// Close closes reader.
+	Close() error
+}
+
+// RunReader runs reader as squad consumer, e.g.
+//
+//	kafkago.RunReader(s, r, func(ctx context.Context, msg kafka.Message) error { ... })
+//
+// Messages are fetched within consume context and handled within handle context,
+// so when squad begins shutdown reader stops fetching, while in-flight message is
+// handled and committed during grace period. Reader is closed before consumer returns.
+// Error of handling or commit fails consumer.
+func RunReader[M any](s *squad.Squad, r Reader[M], handle func(context.Context, M) error, opts ...squad.TaskOption) {
+	s.RunConsumer(func(consumeCtx, handleCtx context.Context) error {
+		return errors.Join(read(consumeCtx, handleCtx, r, handle), r.Close())
+	}, opts...)
+}
+
+func read[M any](consumeCtx, handleCtx context.Context, r Reader[M], handle func(context.Context, M) error) error {
+	for {
+		msg, err := r.FetchMessage(consumeCtx)
+		if err != nil {
+			if consumeCtx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if err := handle(handleCtx, msg); err != nil {
+			return err
+		}
+		if err := r.CommitMessages(handleCtx, msg); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package kafkago
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+type testReader struct {
+	messages  chan string
+	mtx       sync.Mutex
+	committed []string
+	closed    atomic.Bool
+}
+
+func (r *testReader) FetchMessage(ctx context.Context) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case msg := <-r.messages:
+		return msg, nil
+	}
+}
+
+func (r *testReader) CommitMessages(ctx context.Context, msgs ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *testReader) commits() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.committed
+}
+
+func (r *testReader) Close() error {
+	r.closed.Store(true)
+	return nil
+}
+
+func TestRunReader(t *testing.T) {
+	s, err := squad.New(squad.WithManualTrigger(squad.WithGracefulPeriod(time.Second), squad.WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+
+	r := &testReader{messages: make(chan string)}
+	handling := make(chan struct{})
+	RunReader(s, r, func(ctx context.Context, msg string) error {
+		if msg == "slow" {
+			close(handling)
+			// NOTE: in-flight message is handled after reader stops fetching.
+			time.Sleep(100 * time.Millisecond)
+		}
+		return ctx.Err()
+	})
+
+	r.messages <- "fast"
+	r.messages <- "slow"
+	<-handling
+	s.Stop(nil)
+
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, []string{"fast", "slow"}, r.commits())
+	assert.True(t, r.closed.Load())
+}
+
+func TestRunReader_Failure(t *testing.T) {
+	s, err := squad.New()
+	assert.NoError(t, err)
+
+	errHandle := errors.New("handle failed")
+	r := &testReader{messages: make(chan string, 1)}
+	r.messages <- "poison"
+	RunReader(s, r, func(context.Context, string) error { return errHandle })
+
+	assert.ErrorIs(t, s.Wait(), errHandle)
+	assert.Empty(t, r.commits())
+	assert.True(t, r.closed.Load())
+}
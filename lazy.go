@@ -0,0 +1,61 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSubsystemClosed is returned by LazySubsystem.Init after squad has closed subsystem.
+var ErrSubsystemClosed = errors.New("subsystem is closed")
+
+// LazySubsystem is a subsystem, which is initialized on first use instead of squad bootstrap.
+type LazySubsystem struct {
+	initFn, closeFn func(context.Context) error
+
+	mtx         sync.Mutex
+	initialized bool
+	closed      bool
+	err         error
+}
+
+// Lazy returns subsystem, which will be initialized by first call of Init.
+// Cleanup function runs on squad shutdown only if subsystem has been initialized successfully.
+func Lazy(initFn, closeFn func(context.Context) error) *LazySubsystem {
+	return &LazySubsystem{initFn: initFn, closeFn: closeFn}
+}
+
+// Init initializes subsystem once, subsequent calls return result of first initialization.
+func (l *LazySubsystem) Init(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.closed {
+		return ErrSubsystemClosed
+	}
+	if !l.initialized {
+		l.err = l.initFn(ctx)
+		l.initialized = true
+	}
+	return l.err
+}
+
+func (l *LazySubsystem) close(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.closed = true
+	if !l.initialized || l.err != nil || l.closeFn == nil {
+		return nil
+	}
+	return l.closeFn(ctx)
+}
+
+// WithLazySubsystem is Squad option that adds lazy subsystem to squad shutdown.
+func WithLazySubsystem(subsystems ...*LazySubsystem) Option {
+	return func(s *Squad) {
+		for _, subsystem := range subsystems {
+			s.addCleanup(tierStorage, "", subsystem.close)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+)
+
+// ErrListenerStoppedAccept is returned by Accept of graceful listener after squad begins shutdown.
+var ErrListenerStoppedAccept = errors.New("listener stopped accept")
+
+// tcpGracefulListener is a listener, which stops accepting new connections
+// when context is done, already accepted connections are not affected.
+type tcpGracefulListener struct {
+	net.Listener
+	ctx     context.Context
+	stop    func() bool
+	onClose func()
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newGracefulListener(ctx context.Context, ln net.Listener, onClose func()) *tcpGracefulListener {
+	l := &tcpGracefulListener{Listener: ln, ctx: ctx, onClose: onClose}
+	l.stop = context.AfterFunc(ctx, func() { _ = l.close() })
+	return l
+}
+
+func (l *tcpGracefulListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil && l.ctx.Err() != nil {
+		return nil, ErrListenerStoppedAccept
+	}
+	return conn, err
+}
+
+// Close closes listener, it may be called by both squad and server.
+func (l *tcpGracefulListener) Close() error {
+	l.stop()
+	return l.close()
+}
+
+func (l *tcpGracefulListener) close() error {
+	l.closeOnce.Do(func() {
+		l.closeErr = l.Listener.Close()
+		l.onClose()
+	})
+	return l.closeErr
+}
+
+// Listen announces on the local network address like net.Listen, listener stops
+// accepting new connections when squad begins shutdown. Squad closes listener
+// at the latest before cleanup functions, so listener of server failed to start
+// doesn't keep port bound until process exit. Listener inherited by socket activation
+// is returned instead of binding new one, see WithInheritedListeners. Stale file
+// of unix socket is removed before binding.
+func (s *Squad) Listen(network, address string) (net.Listener, error) {
+	if ln := s.inheritedListener(network, address); ln != nil {
+		return ln, nil
+	}
+	if network == "unix" {
+		removeStaleSocket(address)
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return s.trackListener(ln), nil
+}
+
+// removeStaleSocket removes unix socket file left by crashed process,
+// socket served by other process is kept, so binding fails.
+func removeStaleSocket(path string) {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return
+	}
+	_ = os.Remove(path)
+}
+
+// WithEarlyBinding is a Squad option that binds listeners of servers launched by
+// RunServer at once, but serving begins after bootstrap has been completed, e.g. with
+// WithDeferredBootstrap. So port conflict fails bootstrap fast and squad is not ready,
+// but port is not served until subsystems have been initialized.
+func WithEarlyBinding() Option {
+	return func(s *Squad) {
+		s.earlyBinding = true
+	}
+}
+
+func (s *Squad) setBindErr(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.bindErr = errors.Join(s.bindErr, err)
+}
+
+// bindFailed reports failure of early binding, if any.
+func (s *Squad) bindFailed() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.bindErr
+}
+
+// trackListener makes listener graceful and closed by squad.
+func (s *Squad) trackListener(ln net.Listener) net.Listener {
+	var l *tcpGracefulListener
+	l = newGracefulListener(s.drainContext(), ln, func() { s.removeListener(l) })
+	s.addListener(l)
+	return l
+}
+
+func (s *Squad) addListener(ln net.Listener) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[ln] = struct{}{}
+}
+
+func (s *Squad) removeListener(ln net.Listener) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.listeners, ln)
+}
+
+// closeListener closes listener and removes it from open ones.
+func (s *Squad) closeListener(ln net.Listener) error {
+	s.removeListener(ln)
+	if err := ln.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// closeListeners closes listeners, which are still open after members stopped.
+func (s *Squad) closeListeners() error {
+	s.mtx.Lock()
+	listeners := make([]net.Listener, 0, len(s.listeners))
+	for ln := range s.listeners {
+		listeners = append(listeners, ln)
+	}
+	s.mtx.Unlock()
+
+	var err error
+	for _, ln := range listeners {
+		err = errors.Join(err, s.closeListener(ln))
+	}
+	return err
+}
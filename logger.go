@@ -0,0 +1,42 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// WithLogger is a Squad option that sets logger of squad lifecycle events:
+// start, received signal, beginning of graceful period, failures of members,
+// every cleanup function and final exit. By default squad is silent.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Squad) {
+		s.logger = logger
+	}
+}
+
+func (s *Squad) log(level slog.Level, msg string, args ...any) {
+	if s.logger != nil {
+		s.logger.Log(context.Background(), level, msg, args...)
+	}
+}
+
+// runCleanup runs cleanup function logging its start and result.
+func (s *Squad) runCleanup(ctx context.Context, c *cleanup) error {
+	s.log(slog.LevelDebug, "cleanup started", "name", c.member())
+
+	start := time.Now()
+	err := s.trackDrainPhases(ctx, c, func(ctx context.Context) error {
+		return c.run(ctx, s.cleanupBackoff, s.guarded)
+	})
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		s.log(slog.LevelWarn, "cleanup timed out", "name", c.member(), "duration", time.Since(start))
+	case err != nil:
+		s.log(slog.LevelError, "cleanup failed", "name", c.member(), "duration", time.Since(start), "error", err)
+	default:
+		s.log(slog.LevelDebug, "cleanup finished", "name", c.member(), "duration", time.Since(start))
+	}
+	return err
+}
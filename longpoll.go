@@ -0,0 +1,24 @@
+package squad
+
+import "context"
+
+// Draining returns channel, which is closed when squad begins shutdown.
+func (s *Squad) Draining() <-chan struct{} {
+	return s.drainContext().Done()
+}
+
+// LongPoll waits value from events for long-polling handler. It returns ErrShuttingDown
+// as soon as squad begins shutdown, so handler can respond immediately with empty or
+// retry response instead of pinning connection for entire grace period.
+func LongPoll[T any](ctx context.Context, s *Squad, events <-chan T) (T, error) {
+	var zero T
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-s.Draining():
+		return zero, ErrShuttingDown
+	case event := <-events:
+		return event, nil
+	}
+}
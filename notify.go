@@ -0,0 +1,32 @@
+package squad
+
+import (
+	"log/slog"
+	"net"
+	"os"
+)
+
+// WithSystemdNotify is a Squad option that reports lifecycle of squad to systemd by
+// sd_notify protocol, if NOTIFY_SOCKET is set: READY=1 after bootstrap has been completed
+// and STOPPING=1 when squad begins shutdown.
+func WithSystemdNotify() Option {
+	return func(s *Squad) {
+		s.notifySocket = os.Getenv("NOTIFY_SOCKET")
+	}
+}
+
+// notifySystemd sends state to systemd, failure is only logged.
+func (s *Squad) notifySystemd(state string) {
+	if s.notifySocket == "" {
+		return
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: s.notifySocket, Net: "unixgram"})
+	if err == nil {
+		_, err = conn.Write([]byte(state))
+		err = joinErrors(err, conn.Close())
+	}
+	if err != nil {
+		s.log(slog.LevelWarn, "systemd notification failed", "state", state, "error", err)
+	}
+}
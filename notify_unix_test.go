@@ -0,0 +1,42 @@
+//go:build unix
+
+package squad
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSquad_SystemdNotify(t *testing.T) {
+	dir, err := os.MkdirTemp("", "notify")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	assert.NoError(t, err)
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	receive := func() string {
+		buf := make([]byte, 64)
+		assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := conn.Read(buf)
+		assert.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	testGroup, err := New(ProfileSystemd(WithSignals(syscall.SIGUSR1), WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+	assert.Equal(t, "READY=1", receive())
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, "STOPPING=1", receive())
+}
@@ -2,8 +2,13 @@ package squad
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
 	"os/signal"
-	"syscall"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -35,22 +40,121 @@ func WithShutdownInGracePriod(timeout time.Duration) ShutdownOpt {
 	}
 }
 
+// WithImmediateCancel cancels squad context right after receiving signal
+// without graceful period, cancellation functions still have shutdown timeout.
+// Suitable for services without inbound traffic.
+func WithImmediateCancel() ShutdownOpt {
+	return func(s *shutdown) {
+		s.immediateCancel = true
+	}
+}
+
+// WithPreShutdownDelay sets delay between beginning of shutdown and graceful period,
+// during which squad is not ready, but servers keep serving as usual, e.g. while
+// Kubernetes removes pod from endpoints asynchronously after SIGTERM.
+func WithPreShutdownDelay(delay time.Duration) ShutdownOpt {
+	return func(s *shutdown) {
+		s.preShutdownDelay = delay
+	}
+}
+
+// WithTrigger sets channel, closing or sending to which acts exactly
+// like receiving signal, e.g. ctx.Done() of outer context.
+func WithTrigger(trigger <-chan struct{}) ShutdownOpt {
+	return func(s *shutdown) {
+		s.trigger = trigger
+	}
+}
+
+// WithSignals sets signals handled by signal handler instead of default ones,
+// e.g. to shut down squads of one process independently.
+func WithSignals(signals ...os.Signal) ShutdownOpt {
+	return func(s *shutdown) {
+		s.signalSet = signals
+	}
+}
+
+// SignalAction is reaction of signal handler on signal.
+type SignalAction int
+
+const (
+	// SignalGraceful begins graceful shutdown.
+	SignalGraceful SignalAction = iota + 1
+	// SignalImmediate begins shutdown canceling squad context immediately.
+	SignalImmediate
+	// SignalIgnore ignores signal.
+	SignalIgnore
+	// SignalReload invokes reload functions, see WithReloader.
+	SignalReload
+	// SignalDiagnostics logs status of members and dumps stacks of all goroutines to stderr.
+	SignalDiagnostics
+)
+
+// WithSignalPolicy sets reaction of signal handler on given signals, which are handled
+// in addition to default ones or ones set by WithSignals, e.g. graceful drain on SIGTERM,
+// but immediate cancellation on SIGINT in local development.
+func WithSignalPolicy(policy map[os.Signal]SignalAction) ShutdownOpt {
+	return func(s *shutdown) {
+		s.signalPolicy = policy
+	}
+}
+
+// WithSignalAction sets reaction of signal handler on given signal in addition
+// to ones set by WithSignalPolicy, e.g. SignalDiagnostics on SIGUSR1.
+func WithSignalAction(sig os.Signal, action SignalAction) ShutdownOpt {
+	return func(s *shutdown) {
+		policy := maps.Clone(s.signalPolicy)
+		if policy == nil {
+			policy = make(map[os.Signal]SignalAction)
+		}
+		policy[sig] = action
+		s.signalPolicy = policy
+	}
+}
+
+// WithGracePeriodFile sets file, which will be re-read every interval
+// for actual graceful period, e.g. mounted by Kubernetes Downward API.
+// File must contain number of seconds or duration string, until valid
+// content appears configured graceful period is used. Non-positive interval
+// fails New with ErrInvalidOption.
+func WithGracePeriodFile(path string, interval time.Duration) ShutdownOpt {
+	return func(s *shutdown) {
+		s.gracePeriodFile = path
+		s.watchInterval = interval
+	}
+}
+
 // WithSignalHandler is a Squad option that adds signal handling
 // goroutine to the squad. This goroutine will exit on SIGINT or SIGHUP
-// or SIGTERM or SIGQUIT with graceful timeount and reserves
-// time for the release of resources.
+// or SIGTERM or SIGQUIT, on Windows on interrupt or closing of console, logoff
+// and system shutdown, unless other signals are set by WithSignals,
+// with graceful timeount and reserves time for the release of resources.
 func WithSignalHandler(opts ...ShutdownOpt) Option {
-	config := shutdown{
-		gracefulPeriod:  defaultContextGracePeriod,
-		shutdownTimeout: defaultCancellationDelay,
-	}
+	config := newShutdown(opts...)
+	config.option, config.signals = "WithSignalHandler", true
 
-	for _, opt := range opts {
-		opt(&config)
+	return func(squad *Squad) {
+		squad.configureShutdown(config)
 	}
+}
+
+// WithManualTrigger is a Squad option that adds same graceful shutdown
+// as WithSignalHandler, but shutdown begins only by calling Squad.Stop.
+func WithManualTrigger(opts ...ShutdownOpt) Option {
+	config := newShutdown(opts...)
+	config.option = "WithManualTrigger"
+
 	return func(squad *Squad) {
-		squad.cancellationDelay = config.shutdownTimeout
-		squad.serverContext = handleSignals(config.delay(), squad.cancel)
+		squad.configureShutdown(config)
+	}
+}
+
+// WithContext is a Squad option that sets parent of squad context, so members
+// receive its values, e.g. trace IDs. Cancellation of parent begins squad shutdown
+// same as calling Squad.Stop, its cause is reported as shutdown reason.
+func WithContext(ctx context.Context) Option {
+	return func(s *Squad) {
+		s.parent = ctx
 	}
 }
 
@@ -67,44 +171,301 @@ func WithBootstrap(fns ...func(context.Context) error) Option {
 	}
 }
 
+// WithStartupTimeout is a Squad option that limits time of all bootstrap functions,
+// New fails with ErrBootstrapTimeout after timeout elapsed.
+func WithStartupTimeout(timeout time.Duration) Option {
+	return func(s *Squad) {
+		s.startupTimeout = timeout
+	}
+}
+
+// WithDeferredBootstrap is a Squad option that defers bootstrap functions
+// until Wait is called, members are launched after bootstrap has been completed.
+// Beginning of shutdown interrupts bootstrap and cleanup functions roll back
+// already initialized subsystems.
+func WithDeferredBootstrap() Option {
+	return func(s *Squad) {
+		s.deferBootstrap = true
+	}
+}
+
+// WithBootstrapStage is a Squad option that adds named stage of bootstrap functions.
+// Stages are executed sequentially in order of adding after functions added by WithBootstrap,
+// functions within stage are executed concurrently.
+func WithBootstrapStage(name string, fns ...func(context.Context) error) Option {
+	return func(s *Squad) {
+		stage := bootstrapStage{name: name}
+		for _, fn := range fns {
+			if fn == nil {
+				continue
+			}
+			stage.fns = append(stage.fns, fn)
+		}
+		s.stages = append(s.stages, stage)
+	}
+}
+
 // WithCloses is a Squad options that adds cleanup functions,
-// which will be executed after squad stopped.
+// which will be executed after squad stopped. Deadline of context passed
+// to cleanup functions reflects remaining part of shutdown timeout.
 func WithCloses(fns ...func(context.Context) error) Option {
 	return func(s *Squad) {
-		s.cancellationFuncs = append(s.cancellationFuncs, fns...)
+		for _, fn := range fns {
+			s.addCleanup(tierFlush, "", fn)
+		}
+	}
+}
+
+// WithClose is a Squad option that adds cleanup function with own timeout,
+// e.g. short one for metrics flush, which is enforced within shutdown timeout.
+func WithClose(timeout time.Duration, fn func(context.Context) error) Option {
+	return func(s *Squad) {
+		s.addCleanup(tierFlush, "", fn).timeout = timeout
+	}
+}
+
+// WithCleanupRetry is a Squad option that retries failed cleanup functions
+// with exponential backoff starting from given one, while shutdown timeout allows.
+func WithCleanupRetry(backoff time.Duration) Option {
+	return func(s *Squad) {
+		s.cleanupBackoff = backoff
+	}
+}
+
+// WithLowOverhead is a Squad option that disables optional instrumentation
+// of squad goroutines: pprof labels and execution trace annotations.
+// Suitable for latency-sensitive services embedding squad in hot paths.
+func WithLowOverhead() Option {
+	return func(s *Squad) {
+		s.lowOverhead = true
+	}
+}
+
+// SubsystemOpt is an option that can be applied to subsystem.
+type SubsystemOpt func(*subsystem)
+
+// WithSubsystemName sets name of subsystem, which labels its errors and health.
+func WithSubsystemName(name string) SubsystemOpt {
+	return func(s *subsystem) {
+		s.name = name
+	}
+}
+
+// WithHealthCheck sets health check of subsystem, which is used by Squad.Ready.
+func WithHealthCheck(fn func(context.Context) error) SubsystemOpt {
+	return func(s *subsystem) {
+		s.health = fn
 	}
 }
 
 // WithSubsystem is Squad option that add init and cleanup functions
 // for given subsystem witll be executed before and after squad ran.
-func WithSubsystem(initFn, closeFn func(context.Context) error) Option {
+func WithSubsystem(initFn, closeFn func(context.Context) error, opts ...SubsystemOpt) Option {
+	sub := &subsystem{}
+	for _, opt := range opts {
+		opt(sub)
+	}
+
 	return func(s *Squad) {
-		s.bootstraps = append(s.bootstraps, initFn)
-		s.cancellationFuncs = append(s.cancellationFuncs, closeFn)
+		if initFn != nil {
+			s.bootstraps = append(s.bootstraps, sub.wrap(initFn))
+		}
+		s.addCleanup(tierStorage, sub.name, sub.wrap(closeFn))
+		if sub.health != nil {
+			s.subsystems = append(s.subsystems, sub)
+		}
 	}
 }
 
-func handleSignals(delay time.Duration, cancel func()) context.Context {
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGQUIT)
+func (s *Squad) handleSignals(actions map[os.Signal]SignalAction) {
+	received := make(chan os.Signal, 1)
+	for sig := range actions {
+		signal.Notify(received, sig)
+	}
 
 	go func() {
-		defer stop()
-		<-ctx.Done()
-		// NOTE: After receiving signal shut down server, and
+		defer signal.Stop(received)
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case sig := <-received:
+				s.log(slog.LevelInfo, "signal received", "signal", sig.String())
+				s.onSignal(sig, actions[sig])
+			}
+		}
+	}()
+}
+
+func (s *Squad) onSignal(sig os.Signal, action SignalAction) {
+	reason, cause := ShutdownReason{Kind: ReasonSignal, Signal: sig}, &SignalError{Signal: sig}
+
+	switch action {
+	case SignalGraceful:
+		s.beginShutdown(reason, cause)
+	case SignalImmediate:
+		s.beginShutdown(reason, cause)
+		s.cancelCause(cause)
+	case SignalReload:
+		s.reload()
+	case SignalDiagnostics:
+		s.dumpDiagnostics(os.Stderr)
+	case SignalIgnore:
+	}
+}
+
+// watchParent begins shutdown, when parent context is done.
+func (s *Squad) watchParent() {
+	stop := context.AfterFunc(s.parent, func() {
+		cause := context.Cause(s.parent)
+		s.beginShutdown(ShutdownReason{Kind: ReasonStopped, Err: cause}, cause)
+	})
+	context.AfterFunc(s.ctx, func() { stop() })
+}
+
+// configureShutdown collects graceful shutdown configuration, which is installed
+// after all options have been applied, see Squad.setup.
+func (s *Squad) configureShutdown(config shutdown) {
+	if s.shutdownConfig != nil {
+		s.conflict(s.shutdownConfig.option, config.option)
+	}
+	if config.immediateCancel && config.gracePeriodFile != "" {
+		s.conflict(config.option+"(WithImmediateCancel)", config.option+"(WithGracePeriodFile)")
+	}
+	if config.gracePeriodFile != "" && config.watchInterval <= 0 {
+		s.conflicts = append(s.conflicts, fmt.Errorf("%w: %s(WithGracePeriodFile) interval must be positive, got %v",
+			ErrInvalidOption, config.option, config.watchInterval))
+	}
+	s.shutdownConfig = &config
+}
+
+// setupShutdown installs graceful shutdown: after draining begins
+// first of all servers go down, and after delay squad context is canceled.
+func (s *Squad) setupShutdown(config shutdown) {
+	s.cancellationDelay = config.shutdownTimeout
+	s.immediateCancel = config.immediateCancel
+	s.preShutdownDelay = config.preShutdownDelay
+	s.gracefulPeriod.Store(int64(config.gracefulPeriod))
+	s.serverContext, s.drain = context.WithCancel(context.Background())
+	// NOTE: shutdown by failure of member drains servers same as signal.
+	context.AfterFunc(s.ctx, s.drain)
+	if config.gracePeriodFile != "" {
+		s.watchGracePeriod(config.gracePeriodFile, config.watchInterval)
+	}
+	if s.standardOrdering {
+		s.orderConsumers()
+	}
+	if config.trigger != nil {
+		go func() {
+			select {
+			case <-s.ctx.Done():
+			case <-config.trigger:
+				s.Stop(nil)
+			}
+		}()
+	}
+
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.serverContext.Done():
+		}
+		// NOTE: After beginning of draining shut down server, and
 		// wait while all active request and operations complete,
-		// after delay cancel squad context.
-		<-time.After(delay)
-		cancel()
+		// after delay of drain timer cancel squad context.
+		if s.drainTimer.wait(s.ctx) {
+			s.cancelCause(s.causeOf())
+		}
+	}()
+}
+
+// watchGracePeriod keeps squad graceful period in sync with given file
+// until squad context is done.
+func (s *Squad) watchGracePeriod(path string, interval time.Duration) {
+	reload := func() {
+		if period, ok := readGracePeriod(path); ok {
+			s.gracefulPeriod.Store(int64(period))
+		}
+	}
+	reload()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				reload()
+			}
+		}
 	}()
+}
+
+func readGracePeriod(path string) (time.Duration, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
 
-	return ctx
+	value := strings.Trim(strings.TrimSpace(string(content)), `"`)
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if period, err := time.ParseDuration(value); err == nil && period >= 0 {
+		return period, true
+	}
+	return 0, false
+}
+
+func newShutdown(opts ...ShutdownOpt) shutdown {
+	config := shutdown{
+		gracefulPeriod:  defaultContextGracePeriod,
+		shutdownTimeout: defaultCancellationDelay,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
 }
 
 type shutdown struct {
+	// option is name of option, which has set configuration.
+	option  string
+	signals bool
+
 	gracefulPeriod  time.Duration
 	shutdownTimeout time.Duration
+	immediateCancel bool
+	// preShutdownDelay precedes graceful period.
+	preShutdownDelay time.Duration
+	trigger          <-chan struct{}
+	signalSet        []os.Signal
+	signalPolicy     map[os.Signal]SignalAction
+
+	// source of actual graceful period.
+	gracePeriodFile string
+	watchInterval   time.Duration
 }
 
-func (s *shutdown) delay() time.Duration {
-	return s.gracefulPeriod - s.shutdownTimeout
+// signalActions returns reactions on all handled signals.
+func (s shutdown) signalActions() map[os.Signal]SignalAction {
+	signals := s.signalSet
+	if len(signals) == 0 {
+		signals = defaultSignals
+	}
+
+	actions := make(map[os.Signal]SignalAction, len(signals)+len(s.signalPolicy))
+	for _, sig := range signals {
+		actions[sig] = SignalGraceful
+	}
+	for sig, action := range s.signalPolicy {
+		actions[sig] = action
+	}
+	return actions
 }
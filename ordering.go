@@ -0,0 +1,161 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/moeryomenko/synx"
+)
+
+// WithStandardOrdering is a Squad option that orders graceful shutdown
+// by how members have been registered: first servers are drained,
+// then consumers stop receiving events/messages, then cleanup functions
+// of members and WithCloses flush buffers, and finally subsystems are closed.
+func WithStandardOrdering() Option {
+	return func(s *Squad) {
+		s.standardOrdering = true
+	}
+}
+
+// cleanup tiers of standard ordering.
+const (
+	tierFlush = iota
+	tierStorage
+)
+
+// orderConsumers stops consumers after all servers have been drained.
+func (s *Squad) orderConsumers() {
+	s.serversDrained, s.stopConsumers = context.WithCancel(context.Background())
+
+	go func() {
+		defer s.stopConsumers()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.serverContext.Done():
+		}
+
+		select {
+		case <-s.ctx.Done():
+		case <-waitDone(&s.servers):
+		}
+	}()
+}
+
+// consumeContext returns context for consumer events/messages,
+// which is done when squad context is done or consumers are stopped.
+func (s *Squad) consumeContext(ctx context.Context) (context.Context, func()) {
+	if s.serversDrained == nil {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.serversDrained, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// cleanupTiers returns cleanup functions grouped by tiers of shutdown, without
+// standard ordering all of them run at once, unless shutdown phases must run
+// before subsystems are closed.
+func (s *Squad) cleanupTiers() [][]*cleanup {
+	if !s.standardOrdering && len(s.phases) == 0 {
+		return [][]*cleanup{s.cancellationFuncs}
+	}
+
+	tiers := make([][]*cleanup, tierStorage+1)
+	for _, c := range s.cancellationFuncs {
+		tiers[c.tier] = append(tiers[c.tier], c)
+	}
+	return tiers
+}
+
+// runCleanupTiers runs tiers of cleanup functions one after another with shutdown phases
+// after flush tier and then closes subsystems with dependencies, failure of tier or phase
+// does not prevent running next ones.
+func (s *Squad) runCleanupTiers(ctx context.Context) error {
+	var errs []error
+	for i, tier := range s.cleanupTiers() {
+		group := synx.NewErrGroup(ctx)
+		for _, cancelFn := range tier {
+			cancelFn := cancelFn
+			group.Go(func(ctx context.Context) error {
+				return s.runCleanup(ctx, cancelFn)
+			})
+		}
+		errs = append(errs, group.Wait())
+		if i == tierFlush {
+			errs = append(errs, s.runPhases(ctx))
+		}
+	}
+	return joinErrors(append(errs, s.closeDependents(ctx))...)
+}
+
+// WithShutdownPhase is a Squad option that adds named phase of cleanup functions
+// with own timeout. Phases are executed sequentially in order of adding after cleanup
+// functions of members and WithCloses, but before subsystems are closed even without
+// WithStandardOrdering, so phase may still use subsystems. Functions within phase are
+// executed concurrently. Phase timeout is limited by shutdown timeout, which is shared
+// by all cleanup functions.
+func WithShutdownPhase(name string, timeout time.Duration, fns ...func(context.Context) error) Option {
+	return func(s *Squad) {
+		phase := shutdownPhase{name: name, timeout: timeout}
+		for _, fn := range fns {
+			if fn == nil {
+				continue
+			}
+			phase.cleanups = append(phase.cleanups, &cleanup{fn: fn, report: CleanupReport{Name: name}})
+		}
+		s.phases = append(s.phases, phase)
+	}
+}
+
+type shutdownPhase struct {
+	name     string
+	timeout  time.Duration
+	cleanups []*cleanup
+}
+
+// runPhases runs shutdown phases one after another bounded by ctx,
+// failure of phase does not prevent running next ones.
+func (s *Squad) runPhases(ctx context.Context) error {
+	var errs []error
+	for _, phase := range s.phases {
+		if err := s.runPhase(ctx, phase); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown phase %s: %w", phase.name, err))
+		}
+	}
+	return joinErrors(errs...)
+}
+
+func (s *Squad) runPhase(ctx context.Context, phase shutdownPhase) error {
+	ctx, end := s.traceTask(ctx, "squad.shutdown."+phase.name)
+	defer end()
+
+	ctx, cancel := context.WithTimeout(ctx, phase.timeout)
+	defer cancel()
+
+	group := synx.NewErrGroup(ctx)
+	for _, cancelFn := range phase.cleanups {
+		cancelFn := cancelFn
+		group.Go(func(ctx context.Context) error {
+			return s.runCleanup(ctx, cancelFn)
+		})
+	}
+	return group.Wait()
+}
+
+// joinErrors joins non-nil errors keeping single error as is.
+func joinErrors(errs ...error) error {
+	errs = slices.DeleteFunc(errs, func(err error) bool { return err == nil })
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
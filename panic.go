@@ -0,0 +1,117 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// panicRestartDelay is pause before restart of panicked member.
+const panicRestartDelay = 100 * time.Millisecond
+
+// PanicPolicy defines reaction of squad on panic in member.
+type PanicPolicy int
+
+const (
+	// PanicShutdown reports panic as member failure, which stops squad.
+	PanicShutdown PanicPolicy = iota + 1
+	// PanicRestart restarts panicked member.
+	PanicRestart
+	// PanicIsolate reports panic as member error without stopping squad.
+	PanicIsolate
+)
+
+// WithPanicPolicy sets reaction of squad on panic in member,
+// by default squad policy is used, see WithDefaultPanicPolicy.
+func WithPanicPolicy(policy PanicPolicy) TaskOption {
+	return func(t *task) {
+		t.panicPolicy = policy
+	}
+}
+
+// WithDefaultPanicPolicy is a Squad option that sets reaction on panic
+// for members without own policy, by default it is PanicShutdown.
+func WithDefaultPanicPolicy(policy PanicPolicy) Option {
+	return func(s *Squad) {
+		s.panicPolicy = policy
+	}
+}
+
+// WithPanicHandler is a Squad option that sets handler of recovered panics
+// in members and cleanup functions, e.g. for reporting to error tracker.
+// Handler receives panic value and stack trace of panicked goroutine.
+func WithPanicHandler(handler func(value any, stack []byte)) Option {
+	return func(s *Squad) {
+		s.panicHandler = handler
+	}
+}
+
+// PanicError is an error of recovered panic.
+type PanicError struct {
+	Value any
+	// Stack is stack trace of panicked goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// runTask runs member applying its panic policy.
+func (s *Squad) runTask(ctx context.Context, t *task, fn func(context.Context) error) error {
+	for {
+		t.liveness.started()
+		err := s.guarded(ctx, t.name, phaseRun, fn)
+		t.liveness.stopped(err)
+
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			return err
+		}
+
+		switch s.policyOf(t) {
+		case PanicRestart:
+			if waitBackoff(ctx, panicRestartDelay) {
+				t.liveness.restarted()
+				continue
+			}
+			return err
+		case PanicIsolate:
+			s.appendErr(&TaskError{Name: t.name, Err: err})
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+func (s *Squad) policyOf(t *task) PanicPolicy {
+	if t.panicPolicy != 0 {
+		return t.panicPolicy
+	}
+	return s.panicPolicy
+}
+
+// recovered calls fn converting its panic into PanicError.
+func (s *Squad) recovered(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := &PanicError{Value: value, Stack: debug.Stack()}
+			if s.panicHandler != nil {
+				s.panicHandler(value, panicErr.Stack)
+			}
+			err = panicErr
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// guarded runs fn labeled by member and phase converting its panic into PanicError.
+func (s *Squad) guarded(ctx context.Context, member, phase string, fn func(context.Context) error) error {
+	return s.recovered(ctx, func(ctx context.Context) error {
+		return s.labeled(ctx, member, phase, fn)
+	})
+}
@@ -0,0 +1,36 @@
+package squad
+
+import (
+	"context"
+	"time"
+)
+
+// RunPeriodic runs fn every interval, e.g. background sweeper. When squad begins shutdown
+// new runs are not scheduled, but in-flight run keeps going until squad context is canceled.
+// Failed run stops squad same as failure of member launched by Run.
+func (s *Squad) RunPeriodic(interval time.Duration, fn func(context.Context) error, opts ...TaskOption) {
+	t := s.newTask(opts)
+	t.background = true
+
+	s.goTask(t, func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.drainContext().Done():
+				return nil
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			if s.drainContext().Err() != nil {
+				return nil
+			}
+
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Pool is a pool of identical workers run as one squad member.
+type Pool struct {
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// RunPool runs n identical workers as one squad member. Failure of worker stops
+// other workers of pool and signals all group members to stop, error of member
+// joins errors of workers identified by their indexes.
+func (s *Squad) RunPool(n int, worker func(context.Context) error, opts ...TaskOption) *Pool {
+	p := &Pool{stopped: make(chan struct{})}
+
+	t := s.newTask(opts)
+	s.goTask(t, func(ctx context.Context) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-p.stopped:
+				cancel()
+			}
+		}()
+
+		// NOTE: worker runs in own goroutine, so its panic is recovered there
+		// and reported as error of worker, which is subject to panic policy of member.
+		return p.run(ctx, cancel, n, func(ctx context.Context) error {
+			return s.guarded(ctx, t.name, phaseRun, worker)
+		})
+	})
+	return p
+}
+
+// Stop stops workers of pool without stopping squad.
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() { close(p.stopped) })
+}
+
+func (p *Pool) run(ctx context.Context, cancel func(), n int, worker func(context.Context) error) error {
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		errs []error
+	)
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := worker(ctx)
+			if err == nil || p.isStopped() && errors.Is(err, context.Canceled) {
+				return
+			}
+			cancel()
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			errs = append(errs, fmt.Errorf("worker %d: %w", i, err))
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (p *Pool) isStopped() bool {
+	select {
+	case <-p.stopped:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,50 @@
+package squad
+
+import "time"
+
+// kubernetesPreShutdownDelay is time for removal of terminating pod from endpoints.
+const kubernetesPreShutdownDelay = 5 * time.Second
+
+// ProfileKubernetes is a Squad option for services running in Kubernetes pods:
+// signal handler keeps pod ready for pre-shutdown delay, while it is removed from
+// endpoints, then servers are drained before consumers, so whole shutdown fits
+// in default termination grace period of pod. Given options override profile ones.
+func ProfileKubernetes(opts ...ShutdownOpt) Option {
+	return combine(
+		WithSignalHandler(append([]ShutdownOpt{
+			WithPreShutdownDelay(kubernetesPreShutdownDelay),
+			WithGracefulPeriod(defaultContextGracePeriod - kubernetesPreShutdownDelay),
+		}, opts...)...),
+		WithStandardOrdering(),
+	)
+}
+
+// ProfileSystemd is a Squad option for services running as systemd units of Type=notify:
+// squad notifies systemd of readiness and beginning of shutdown, which has to complete
+// within default graceful period, well before TimeoutStopSec. Given options override
+// profile ones.
+func ProfileSystemd(opts ...ShutdownOpt) Option {
+	return combine(
+		WithSignalHandler(opts...),
+		WithSystemdNotify(),
+	)
+}
+
+// ProfileCLI is a Squad option for command line tools: squad exits as soon as
+// all jobs have completed and interrupt cancels squad context immediately,
+// so tool stops promptly, see ExitCode. Given options override profile ones.
+func ProfileCLI(opts ...ShutdownOpt) Option {
+	return combine(
+		WithSignalHandler(append([]ShutdownOpt{WithImmediateCancel()}, opts...)...),
+		WithRunUntilComplete(),
+	)
+}
+
+// combine combines options into one.
+func combine(opts ...Option) Option {
+	return func(s *Squad) {
+		for _, opt := range opts {
+			opt(s)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package pubsub contains adapter of Google Cloud Pub/Sub subscriptions
+// to squad consumers, without dependency on Pub/Sub client.
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/moeryomenko/squad"
+)
+
+// Message is a Pub/Sub message, e.g. *pubsub.Message.
+type Message interface {
+	Ack()
+	Nack()
+}
+
+// Subscription is a Pub/Sub subscription, e.g. *pubsub.Subscription,
+// which is Subscription[*pubsub.Message].
+type Subscription[M Message] interface {
+	// Receive pulls messages and calls f for them concurrently within flow control
+	// settings of subscription, until ctx is done and outstanding calls have returned.
+	Receive(ctx context.Context, f func(context.Context, M)) error
+}
+
+// RunSubscription runs subscription as squad consumer, e.g.
+//
+//	pubsub.RunSubscription(s, sub, func(ctx context.Context, msg *pubsub.Message) error { ... })
+//
+// Messages are handled within handle context, acked on success and nacked on error,
+// concurrency of handling is limited by flow control settings of subscription.
+// When squad begins shutdown subscription stops pulling and outstanding messages
+// are handled during grace period. Handle context of messages left after grace period
+// is canceled and their handlers are waited within half of shutdown timeout, so message
+// is settled by result of its handler. Messages, handlers of which have not returned
+// in time, are nacked while handlers may still be running, so they may be processed
+// twice, handlers must be idempotent or respect cancellation of handle context.
+func RunSubscription[M Message](s *squad.Squad, sub Subscription[M], handler func(context.Context, M) error, opts ...squad.TaskOption) {
+	s.RunConsumer(func(consumeCtx, handleCtx context.Context) error {
+		pullCtx, stopPull := context.WithCancel(consumeCtx)
+		defer stopPull()
+		graceCtx, abort := context.WithCancel(handleCtx)
+		defer abort()
+
+		go func() {
+			select {
+			case <-s.Draining():
+			case <-pullCtx.Done():
+			}
+			stopPull()
+
+			timer := time.NewTimer(s.DrainTimer().Remaining())
+			defer timer.Stop()
+			select {
+			case <-graceCtx.Done():
+			case <-timer.C:
+				abort()
+			}
+		}()
+
+		// NOTE: handlers are waited within half of shutdown timeout,
+		// so consumer returns within its stop timeout.
+		wait := s.ShutdownTimeout() / 2
+		err := sub.Receive(pullCtx, func(_ context.Context, msg M) {
+			settle(graceCtx, msg, handler, wait)
+		})
+		if pullCtx.Err() != nil {
+			return nil
+		}
+		return err
+	}, append([]squad.TaskOption{squad.WithStopTimeout(s.ShutdownTimeout())}, opts...)...)
+}
+
+// settle handles message and acks it on success, message is nacked, if handling fails
+// or handler has not returned within wait period after ctx is done.
+func settle[M Message](ctx context.Context, msg M, handler func(context.Context, M) error, wait time.Duration) {
+	result := make(chan error, 1)
+	go func() {
+		result <- handler(ctx, msg)
+	}()
+
+	select {
+	case err := <-result:
+		ack(msg, err)
+		return
+	case <-ctx.Done():
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		ack(msg, err)
+	case <-timer.C:
+		// NOTE: handler is still running, so message may be processed twice.
+		msg.Nack()
+	}
+}
+
+// ack acks message handled successfully and nacks it otherwise.
+func ack[M Message](msg M, err error) {
+	if err != nil {
+		msg.Nack()
+		return
+	}
+	msg.Ack()
+}
@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+// testResults records settlement of test messages.
+type testResults struct {
+	mtx     sync.Mutex
+	settled map[string]string
+}
+
+func (r *testResults) record(id, outcome string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.settled[id] = outcome
+}
+
+func (r *testResults) outcomes() map[string]string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.settled
+}
+
+type testMessage struct {
+	id      string
+	results *testResults
+}
+
+func (m *testMessage) Ack()  { m.results.record(m.id, "ack") }
+func (m *testMessage) Nack() { m.results.record(m.id, "nack") }
+
+type testSubscription struct {
+	messages chan *testMessage
+	err      error
+}
+
+func (sub *testSubscription) Receive(ctx context.Context, f func(context.Context, *testMessage)) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-sub.messages:
+			if sub.err != nil {
+				return sub.err
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f(ctx, msg)
+			}()
+		}
+	}
+}
+
+func TestRunSubscription(t *testing.T) {
+	s, err := squad.New(squad.WithManualTrigger(
+		squad.WithGracefulPeriod(300*time.Millisecond),
+		squad.WithShutdownTimeout(100*time.Millisecond),
+	))
+	assert.NoError(t, err)
+
+	results := &testResults{settled: make(map[string]string)}
+	sub := &testSubscription{messages: make(chan *testMessage)}
+	handling := make(chan struct{}, 4)
+	RunSubscription(s, sub, func(ctx context.Context, msg *testMessage) error {
+		handling <- struct{}{}
+		switch msg.id {
+		case "slow":
+			// NOTE: outstanding message is handled during grace period.
+			time.Sleep(100 * time.Millisecond)
+		case "finishing":
+			// NOTE: message is settled by result of handler, which finishes after cancellation.
+			<-ctx.Done()
+			return nil
+		case "hung":
+			time.Sleep(time.Second)
+		case "bad":
+			return errors.New("handle failed")
+		}
+		return nil
+	})
+
+	for _, id := range []string{"slow", "finishing", "hung", "bad"} {
+		sub.messages <- &testMessage{id: id, results: results}
+		<-handling
+	}
+	s.Stop(nil)
+
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, map[string]string{"slow": "ack", "finishing": "ack", "hung": "nack", "bad": "nack"}, results.outcomes())
+}
+
+func TestRunSubscription_Failure(t *testing.T) {
+	errPermission := errors.New("permission denied")
+
+	s, err := squad.New()
+	assert.NoError(t, err)
+
+	sub := &testSubscription{messages: make(chan *testMessage, 1), err: errPermission}
+	sub.messages <- &testMessage{}
+	RunSubscription(s, sub, func(context.Context, *testMessage) error { return nil })
+
+	assert.ErrorIs(t, s.Wait(), errPermission)
+}
@@ -0,0 +1,87 @@
+package squad
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ReasonKind is kind of event, which has brought squad down.
+type ReasonKind int
+
+const (
+	// ReasonNone means squad has not begun shutdown.
+	ReasonNone ReasonKind = iota
+	// ReasonSignal means shutdown by received signal.
+	ReasonSignal
+	// ReasonTaskFailed means shutdown by failure of member.
+	ReasonTaskFailed
+	// ReasonStopped means programmatic shutdown by Squad.Stop or trigger.
+	ReasonStopped
+	// ReasonCompleted means all members have exited naturally.
+	ReasonCompleted
+)
+
+// ShutdownReason describes first event, which has brought squad down.
+type ShutdownReason struct {
+	Kind ReasonKind
+	// Signal is received signal for ReasonSignal.
+	Signal os.Signal
+	// Err is error of member for ReasonTaskFailed or reason passed to Squad.Stop.
+	Err error
+}
+
+// SignalError is cause of squad context cancellation by received signal, see context.Cause.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("received signal %s", e.Signal)
+}
+
+// ShutdownReason returns reason of squad shutdown, it is ReasonNone
+// until squad begins shutdown.
+func (s *Squad) ShutdownReason() ShutdownReason {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.shutdownReason
+}
+
+// setReason records reason of shutdown and cause of squad context cancellation,
+// only first ones are kept.
+func (s *Squad) setReason(reason ShutdownReason, cause error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.shutdownReason.Kind == ReasonNone {
+		s.shutdownReason = reason
+	}
+	if s.cause == nil {
+		s.cause = cause
+	}
+}
+
+// causeOf returns cause of squad context cancellation.
+func (s *Squad) causeOf() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.cause
+}
+
+func (k ReasonKind) String() string {
+	switch k {
+	case ReasonNone:
+		return "none"
+	case ReasonSignal:
+		return "signal"
+	case ReasonTaskFailed:
+		return "task_failed"
+	case ReasonStopped:
+		return "stopped"
+	case ReasonCompleted:
+		return "completed"
+	default:
+		return "ReasonKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
@@ -0,0 +1,56 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotRefreshed is reported by Squad.Ready until first refresh has been completed.
+var ErrNotRefreshed = errors.New("not refreshed yet")
+
+// RunRefresher runs refresh immediately and then every interval until squad begins shutdown,
+// so refreshed data keeps being served stale during drain. Failed refresh does not stop squad,
+// but result of last refresh is reported by Squad.Ready.
+func (s *Squad) RunRefresher(interval time.Duration, refresh func(context.Context) error, opts ...TaskOption) {
+	t := s.newTask(opts)
+	t.background = true
+	r := &refresher{err: ErrNotRefreshed}
+	s.addHealthCheck(&subsystem{name: t.name, health: r.health})
+
+	s.goTask(t, func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			r.set(refresh(ctx))
+
+			select {
+			case <-s.drainContext().Done():
+				return nil
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// refresher is guarded result of last refresh.
+type refresher struct {
+	mtx sync.Mutex
+	err error
+}
+
+func (r *refresher) set(err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.err = err
+}
+
+func (r *refresher) health(context.Context) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.err
+}
@@ -0,0 +1,61 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+)
+
+// WithReloader is a Squad option that adds reload functions, e.g. re-reading
+// of configuration. They are invoked by Squad.Reload and on SIGHUP, which does not
+// shut down squad then, other signals can trigger reload by SignalReload policy.
+func WithReloader(fns ...func(context.Context) error) Option {
+	return func(s *Squad) {
+		for _, fn := range fns {
+			if fn == nil {
+				continue
+			}
+			s.reloaders = append(s.reloaders, fn)
+		}
+	}
+}
+
+// Reload invokes reload functions sequentially in order of adding,
+// failed reload does not stop squad.
+func (s *Squad) Reload(ctx context.Context) error {
+	var err error
+	for _, fn := range s.reloaders {
+		err = errors.Join(err, fn(ctx))
+	}
+	return err
+}
+
+func (s *Squad) reload() {
+	if err := s.Reload(s.ctx); err != nil {
+		s.log(slog.LevelError, "reload failed", "error", err)
+		return
+	}
+	s.log(slog.LevelInfo, "reloaded")
+}
+
+// setupSignals installs signal handler of shutdown and reload signals.
+func (s *Squad) setupSignals() {
+	actions := make(map[os.Signal]SignalAction)
+	if len(s.reloaders) > 0 {
+		for _, sig := range reloadSignals {
+			actions[sig] = SignalReload
+		}
+	}
+	if config := s.shutdownConfig; config != nil && config.signals {
+		for sig, action := range config.signalActions() {
+			if _, ok := config.signalPolicy[sig]; ok || actions[sig] != SignalReload {
+				actions[sig] = action
+			}
+		}
+	}
+
+	if len(actions) > 0 {
+		s.handleSignals(actions)
+	}
+}
@@ -0,0 +1,163 @@
+package squad
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReportFormat is format of shutdown report file, see WithShutdownReportFile.
+type ReportFormat int
+
+const (
+	// ReportJSON writes report as JSON document.
+	ReportJSON ReportFormat = iota
+	// ReportText writes report as human readable text.
+	ReportText
+)
+
+// WithShutdownReportFile is a Squad option that writes final lifecycle report
+// to file at path before Wait returns, so post-mortem tooling can inspect
+// how process has been shut down even when logs are lost. File is replaced
+// atomically, failure of writing is reported as squad error.
+func WithShutdownReportFile(path string, format ReportFormat) Option {
+	return func(s *Squad) {
+		s.exitHooks = append(s.exitHooks, func(report Report) {
+			s.appendErr(writeReportFile(path, format, s.lifecycleReport(report)))
+		})
+	}
+}
+
+// lifecycleReport is a final lifecycle report of squad written to file.
+type lifecycleReport struct {
+	Reason     string          `json:"reason"`
+	Signal     string          `json:"signal,omitempty"`
+	Cause      string          `json:"cause,omitempty"`
+	DrainedAt  *time.Time      `json:"drained_at,omitempty"`
+	CanceledAt *time.Time      `json:"canceled_at,omitempty"`
+	StoppedAt  time.Time       `json:"stopped_at"`
+	Errors     []string        `json:"errors"`
+	Members    []memberReport  `json:"members"`
+	Cleanups   []cleanupRecord `json:"cleanups"`
+}
+
+type memberReport struct {
+	Name      string `json:"name"`
+	Restarts  int    `json:"restarts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type cleanupRecord struct {
+	Name     string        `json:"name,omitempty"`
+	Ran      bool          `json:"ran"`
+	Attempts int           `json:"attempts"`
+	Duration time.Duration `json:"duration_ns"`
+	Err      string        `json:"error,omitempty"`
+}
+
+func (s *Squad) lifecycleReport(report Report) lifecycleReport {
+	reason := s.ShutdownReason()
+	lr := lifecycleReport{
+		Reason:     reason.Kind.String(),
+		Cause:      errorText(s.causeOf()),
+		DrainedAt:  timeOf(s.drainedAt.Load()),
+		CanceledAt: timeOf(s.canceledAt.Load()),
+		StoppedAt:  time.Now(),
+		Errors:     []string{},
+		Members:    []memberReport{},
+		Cleanups:   make([]cleanupRecord, 0, len(report.Cleanups)),
+	}
+	if reason.Signal != nil {
+		lr.Signal = reason.Signal.String()
+	}
+	for _, err := range s.Errors() {
+		lr.Errors = append(lr.Errors, err.Error())
+	}
+	for _, status := range s.Describe() {
+		lr.Members = append(lr.Members, memberReport{
+			Name:      status.Name,
+			Restarts:  status.Restarts,
+			LastError: errorText(status.LastError),
+		})
+	}
+	for _, c := range report.Cleanups {
+		lr.Cleanups = append(lr.Cleanups, cleanupRecord{
+			Name:     c.Name,
+			Ran:      c.Ran,
+			Attempts: c.Attempts,
+			Duration: c.Duration,
+			Err:      errorText(c.Err),
+		})
+	}
+	return lr
+}
+
+// writeReportFile writes report to temporary file and renames it to path.
+func writeReportFile(path string, format ReportFormat, report lifecycleReport) error {
+	var buf bytes.Buffer
+	if format == ReportText {
+		report.writeText(&buf)
+	} else {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("shutdown report: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("shutdown report: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("shutdown report: %w", err)
+	}
+	return nil
+}
+
+func (r lifecycleReport) writeText(buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "reason: %s\n", r.Reason)
+	if r.Signal != "" {
+		fmt.Fprintf(buf, "signal: %s\n", r.Signal)
+	}
+	if r.Cause != "" {
+		fmt.Fprintf(buf, "cause: %s\n", r.Cause)
+	}
+	fmt.Fprintf(buf, "stopped at: %s\n", r.StoppedAt.Format(time.RFC3339Nano))
+	for _, err := range r.Errors {
+		fmt.Fprintf(buf, "error: %s\n", err)
+	}
+	for _, m := range r.Members {
+		fmt.Fprintf(buf, "member %s: restarts=%d last_error=%q\n", m.Name, m.Restarts, m.LastError)
+	}
+	for _, c := range r.Cleanups {
+		fmt.Fprintf(buf, "cleanup %s: ran=%t attempts=%d duration=%s error=%q\n", c.Name, c.Ran, c.Attempts, c.Duration, c.Err)
+	}
+}
+
+func timeOf(nanos int64) *time.Time {
+	if nanos == 0 {
+		return nil
+	}
+	t := time.Unix(0, nanos)
+	return &t
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
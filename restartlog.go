@@ -0,0 +1,98 @@
+package squad
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// defaultRestartLogInterval is default interval of restart summaries of flapping member.
+const defaultRestartLogInterval = time.Minute
+
+// WithRestartLogInterval is a Squad option that sets interval of restart log of supervised
+// members, by default it is one minute. First restart within interval is logged at once,
+// subsequent ones are aggregated into summary logged at the end of interval, so member
+// flapping during dependency outage logs one line per interval. Such interval is counted
+// as flap of member, see MemberStatus.Flaps.
+func WithRestartLogInterval(interval time.Duration) Option {
+	return func(s *Squad) {
+		s.restartLogInterval = interval
+	}
+}
+
+// restartLog is rate-limited restart log of supervised member.
+type restartLog struct {
+	s *Squad
+	t *task
+
+	mtx   sync.Mutex
+	timer *time.Timer
+	// restarts and err are number of restarts and last error since last logged line.
+	restarts int
+	err      error
+}
+
+func (s *Squad) restartLog(t *task) *restartLog {
+	return &restartLog{s: s, t: t}
+}
+
+// restarting logs restart of member after given error, if it is first restart
+// within interval, otherwise restart is aggregated into summary.
+func (l *restartLog) restarting(err error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.timer != nil {
+		l.restarts++
+		l.err = err
+		return
+	}
+	l.s.log(slog.LevelWarn, "member restarting", "name", l.t.name, "restarts", l.t.liveness.describe().Restarts+1, "error", err)
+	l.timer = time.AfterFunc(l.interval(), l.flush)
+}
+
+// flush logs summary of aggregated restarts, interval is prolonged while member flaps.
+func (l *restartLog) flush() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.timer == nil {
+		return
+	}
+	if l.restarts == 0 {
+		l.timer = nil
+		return
+	}
+	l.summary()
+	l.timer.Reset(l.interval())
+}
+
+// stop logs summary of aggregated restarts, if any, when member has finished.
+func (l *restartLog) stop() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.timer == nil {
+		return
+	}
+	l.timer.Stop()
+	l.timer = nil
+	if l.restarts > 0 {
+		l.summary()
+	}
+}
+
+func (l *restartLog) summary() {
+	l.t.liveness.flapped()
+	status := l.t.liveness.describe()
+	l.s.log(slog.LevelWarn, "member flapping", "name", l.t.name, "restarts", l.restarts,
+		"total_restarts", status.Restarts, "flaps", status.Flaps, "last_error", l.err)
+	l.restarts, l.err = 0, nil
+}
+
+func (l *restartLog) interval() time.Duration {
+	if l.s.restartLogInterval > 0 {
+		return l.s.restartLogInterval
+	}
+	return defaultRestartLogInterval
+}
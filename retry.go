@@ -0,0 +1,82 @@
+package squad
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy defines retries of function by Retry.
+type RetryPolicy struct {
+	Backoff Backoff
+	// MaxAttempts limits number of attempts, non-positive means unlimited.
+	MaxAttempts int
+	// RetryOn reports whether function should be retried after error,
+	// nil means retry on any error.
+	RetryOn func(error) bool
+}
+
+// Retry calls fn until it succeeds or policy is exhausted, it returns last error of fn.
+// Retry is budget-aware: once squad carried by ctx of member or request begins shutdown,
+// see InjectShutdownState, or ctx has deadline, fn is retried only while time left is
+// enough for next backoff, so shutdown is not held by retry loops deep inside members.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(context.Context) error) error {
+	return retry(ctx, policy, fn, nil)
+}
+
+// retry is Retry, which calls onRetry with error of failed attempt before every backoff.
+func retry(ctx context.Context, policy RetryPolicy, fn func(context.Context) error, onRetry func(err error, retries int)) error {
+	for retries := 0; ; retries++ {
+		err := fn(ctx)
+		if err == nil || ctx.Err() != nil || !policy.allows(err, retries) {
+			return err
+		}
+
+		backoff := policy.Backoff.delay(retries)
+		if remaining, ok := retryBudget(ctx); ok && remaining < backoff {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(err, retries)
+		}
+		if !waitBackoff(ctx, backoff) {
+			return err
+		}
+	}
+}
+
+func (p RetryPolicy) allows(err error, retries int) bool {
+	if p.MaxAttempts > 0 && retries+1 >= p.MaxAttempts {
+		return false
+	}
+	return p.RetryOn == nil || p.RetryOn(err)
+}
+
+// retryBudget returns time left for retries: until cancellation of draining squad
+// carried by ctx or until deadline of ctx, whichever is sooner.
+func retryBudget(ctx context.Context) (remaining time.Duration, ok bool) {
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		remaining, ok = time.Until(deadline), true
+	}
+
+	s, hasSquad := ctx.Value(squadKey{}).(*Squad)
+	if !hasSquad || s.ctx.Err() != nil || s.drainContext().Err() == nil {
+		return remaining, ok
+	}
+	if grace := s.drainTimer.Remaining(); !ok || grace < remaining {
+		remaining, ok = grace, true
+	}
+	return remaining, ok
+}
+
+// waitBackoff waits backoff and reports whether ctx is still alive.
+func waitBackoff(ctx context.Context, backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,342 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ServerOptions is lifecycle configuration of http server run by squad.
+type ServerOptions struct {
+	// GracePeriod is time for which server keeps serving requests
+	// after squad begins shutdown.
+	GracePeriod time.Duration
+	// ShutdownTimeout limits graceful shutdown of server, after it elapsed
+	// remaining connections are closed forcibly. Zero means no limit.
+	ShutdownTimeout time.Duration
+	// DisableKeepAlives disables keep-alives when squad begins shutdown,
+	// so clients reconnect to other instances during grace period.
+	DisableKeepAlives bool
+	// MaxConnectionAge closes keep-alive connections older than it, when they
+	// become idle during normal operation, so load balancer rebalances clients
+	// and drain is faster. Zero means no limit.
+	MaxConnectionAge time.Duration
+	// TrackConnections makes shutdown wait, until all accepted connections including
+	// hijacked ones, e.g. websockets, have been closed, within ShutdownTimeout.
+	// Remaining connections are closed forcibly, see GracefulListener.
+	TrackConnections bool
+	// Listener is served instead of binding address of server, e.g. inherited
+	// or unix socket listener. Squad stops it accepting same as one returned by Listen.
+	Listener net.Listener
+}
+
+// RunServer is wrapper function for launch http server,
+// server is shutting down as soon as squad begins shutdown.
+// Address of server may be path of unix socket prefixed by "unix://",
+// socket file is removed when listener is closed.
+func (s *Squad) RunServer(srv *http.Server) {
+	s.RunServerWithOptions(srv, ServerOptions{})
+}
+
+// RunServerWithOptions is wrapper function for launch http server
+// with given lifecycle configuration. When squad begins shutdown
+// server stops accepting new connections, but keeps serving accepted ones
+// until it is shut down.
+func (s *Squad) RunServerWithOptions(srv *http.Server, opts ServerOptions) {
+	s.runHTTPServer(srv, serverAddr(srv, ":http"), opts, srv.Serve)
+}
+
+// RunServerListener is wrapper function for launch http server on given listener,
+// e.g. with SO_REUSEPORT or PROXY protocol, same as RunServer, see ServerOptions.Listener.
+func (s *Squad) RunServerListener(srv *http.Server, ln net.Listener) {
+	s.RunServerWithOptions(srv, ServerOptions{Listener: ln})
+}
+
+// RunServerTLS is wrapper function for launch https server with certificate and key
+// files, same as RunServer. Files may be empty, if srv.TLSConfig provides certificates,
+// e.g. by Certificates or GetCertificate.
+func (s *Squad) RunServerTLS(srv *http.Server, certFile, keyFile string) {
+	s.RunServerTLSWithOptions(srv, certFile, keyFile, ServerOptions{})
+}
+
+// RunServerTLSWithOptions is wrapper function for launch https server with given
+// lifecycle configuration, see RunServerTLS and RunServerWithOptions. TLS handshakes
+// in progress are completed during graceful shutdown, they are limited by
+// ReadHeaderTimeout of server and then by ShutdownTimeout.
+func (s *Squad) RunServerTLSWithOptions(srv *http.Server, certFile, keyFile string, opts ServerOptions) {
+	s.runHTTPServer(srv, serverAddr(srv, ":https"), opts, func(ln net.Listener) error {
+		return srv.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+func (s *Squad) runHTTPServer(srv *http.Server, addr string, opts ServerOptions, serve func(net.Listener) error) {
+	name := srv.Addr
+	if opts.Listener != nil {
+		name = opts.Listener.Addr().String()
+	}
+	t := s.newTask([]TaskOption{WithTaskName("server " + name)})
+	if opts.MaxConnectionAge > 0 {
+		recycleConnections(srv, opts.MaxConnectionAge)
+	}
+
+	listen := func() (net.Listener, error) { return s.Listen(serverNetwork(addr)) }
+	if ln := opts.Listener; ln != nil {
+		ln = s.trackListener(ln)
+		listen = func() (net.Listener, error) { return ln, nil }
+	} else if s.earlyBinding {
+		ln, err := listen()
+		s.setBindErr(err)
+		listen = func() (net.Listener, error) { return ln, err }
+	}
+
+	var shutdowner Shutdowner = srv
+	if opts.TrackConnections {
+		tracked := &trackedServer{Server: srv}
+		listen = tracked.listen(listen)
+		shutdowner = tracked
+	}
+
+	s.runShutdowner(t, func(context.Context) error {
+		ln, err := listen()
+		if err != nil {
+			return err
+		}
+		return serve(ln)
+	}, shutdowner, opts)
+}
+
+// trackedServer is http server, shutdown of which waits accepted connections
+// including hijacked ones, which are not tracked by http.Server.
+type trackedServer struct {
+	*http.Server
+	ln atomic.Pointer[GracefulListener]
+}
+
+func (t *trackedServer) listen(listen func() (net.Listener, error)) func() (net.Listener, error) {
+	return func() (net.Listener, error) {
+		ln, err := listen()
+		if err != nil {
+			return nil, err
+		}
+		tracked := trackConns(ln)
+		t.ln.Store(tracked)
+		return tracked, nil
+	}
+}
+
+// Shutdown shuts down server and waits accepted connections, remaining ones
+// are closed forcibly when ctx is done.
+func (t *trackedServer) Shutdown(ctx context.Context) error {
+	err := t.Server.Shutdown(ctx)
+	ln := t.ln.Load()
+	if ln == nil {
+		return err
+	}
+
+	if err == nil {
+		err = ln.WaitIdle(ctx)
+	}
+	if err != nil {
+		return errors.Join(err, ln.CloseConns())
+	}
+	return nil
+}
+
+// Shutdowner is a server, which can be gracefully shut down, e.g. http.Server.
+type Shutdowner interface {
+	Shutdown(context.Context) error
+}
+
+// RunShutdowner is wrapper function for launch any server by start function,
+// when squad begins shutdown server is gracefully shut down.
+// If server implements io.Closer, it is closed after failed shutdown.
+func (s *Squad) RunShutdowner(start func(context.Context) error, srv Shutdowner, opts ...TaskOption) {
+	s.runShutdowner(s.newTask(opts), start, srv, ServerOptions{})
+}
+
+// Service is a server-shaped dependency, e.g. gRPC server or custom TCP daemon.
+// Start may block until service is stopped or return as soon as service is started.
+type Service interface {
+	Start(context.Context) error
+	// Stop gracefully stops service, it is called when squad begins shutdown.
+	Stop(context.Context) error
+}
+
+// RunService is wrapper function for launch service same as RunShutdowner,
+// when squad begins shutdown service is stopped. Service, Start of which
+// returns at once, is considered running until it is stopped.
+func (s *Squad) RunService(svc Service, opts ...TaskOption) {
+	ctx := s.drainContext()
+	s.runShutdowner(s.newTask(opts), func(taskCtx context.Context) error {
+		if err := svc.Start(taskCtx); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return nil
+	}, serviceShutdowner{svc}, ServerOptions{})
+}
+
+// serviceShutdowner adapts service to Shutdowner.
+type serviceShutdowner struct {
+	svc Service
+}
+
+func (s serviceShutdowner) Shutdown(ctx context.Context) error {
+	return s.svc.Stop(ctx)
+}
+
+func (s *Squad) runShutdowner(t *task, start func(context.Context) error, srv Shutdowner, opts ServerOptions) {
+	t.background = true
+	ctx, shutdowned := s.drainContext(), make(chan struct{})
+
+	s.goTask(t, func(taskCtx context.Context) error {
+		err := start(taskCtx)
+		if errors.Is(err, http.ErrServerClosed) || errors.Is(err, ErrListenerStoppedAccept) {
+			err = nil
+		}
+		// NOTE: server may keep serving accepted connections until it is shut down.
+		if err == nil && ctx.Err() != nil {
+			<-shutdowned
+		}
+		return err
+	})
+
+	// NOTE: After receiving shutdowning signal first of all,
+	// gracefully shuts down the server without interrupting any active connections.
+	s.servers.Add(1)
+	go func() {
+		defer s.servers.Done()
+		defer close(shutdowned)
+
+		<-ctx.Done()
+		s.appendErr(s.labeled(context.WithoutCancel(ctx), t.name, phaseDrain, func(ctx context.Context) error {
+			if srv, ok := srv.(interface{ SetKeepAlivesEnabled(bool) }); ok && opts.DisableKeepAlives {
+				srv.SetKeepAlivesEnabled(false)
+			}
+			<-time.After(opts.GracePeriod)
+
+			return shutdownServer(ctx, srv, opts.ShutdownTimeout)
+		}))
+	}()
+}
+
+func serverAddr(srv *http.Server, fallback string) string {
+	if srv.Addr == "" {
+		return fallback
+	}
+	return srv.Addr
+}
+
+// serverNetwork returns network and address of listener of server address.
+func serverNetwork(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		return "unix", path
+	}
+	return "tcp", addr
+}
+
+func shutdownServer(ctx context.Context, srv Shutdowner, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := srv.Shutdown(ctx)
+	if closer, ok := srv.(io.Closer); ok && errors.Is(err, context.DeadlineExceeded) {
+		return errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+// recycleConnections closes idle connections of server older than given age,
+// each connection has single timer, which is stopped when connection is closed.
+func recycleConnections(srv *http.Server, age time.Duration) {
+	var (
+		mtx   sync.Mutex
+		conns = make(map[net.Conn]*connAge)
+	)
+	expire := func(conn net.Conn) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if c, ok := conns[conn]; ok {
+			c.expired = true
+			if c.state == http.StateIdle {
+				_ = conn.Close()
+			}
+		}
+	}
+
+	srv.ConnState = ChainConnState(srv.ConnState, func(conn net.Conn, state http.ConnState) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		switch state {
+		case http.StateNew:
+			conns[conn] = &connAge{state: state, timer: time.AfterFunc(age, func() { expire(conn) })}
+		case http.StateHijacked, http.StateClosed:
+			if c, ok := conns[conn]; ok {
+				c.timer.Stop()
+				delete(conns, conn)
+			}
+		default:
+			if c, ok := conns[conn]; ok {
+				c.state = state
+				// NOTE: connection expired while active is closed as soon as it becomes idle.
+				if c.expired && state == http.StateIdle {
+					_ = conn.Close()
+				}
+			}
+		}
+	})
+}
+
+// ChainConnState returns hook of http.Server.ConnState, which calls given hooks in order
+// skipping nil ones, so connection tracking of squad and user instrumentation coexist, e.g.
+//
+//	srv.ConnState = squad.ChainConnState(srv.ConnState, metrics.TrackConn)
+//
+// It returns nil, if there are no hooks.
+func ChainConnState(hooks ...func(net.Conn, http.ConnState)) func(net.Conn, http.ConnState) {
+	hooks = slices.DeleteFunc(slices.Clone(hooks), func(hook func(net.Conn, http.ConnState)) bool { return hook == nil })
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	return func(conn net.Conn, state http.ConnState) {
+		for _, hook := range hooks {
+			hook(conn, state)
+		}
+	}
+}
+
+// ChainConnContext returns hook of http.Server.ConnContext, which passes context
+// of connection through given hooks in order skipping nil ones, see ChainConnState.
+// It returns nil, if there are no hooks.
+func ChainConnContext(hooks ...func(context.Context, net.Conn) context.Context) func(context.Context, net.Conn) context.Context {
+	hooks = slices.DeleteFunc(slices.Clone(hooks), func(hook func(context.Context, net.Conn) context.Context) bool { return hook == nil })
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context, conn net.Conn) context.Context {
+		for _, hook := range hooks {
+			ctx = hook(ctx, conn)
+		}
+		return ctx
+	}
+}
+
+// connAge is state of connection recycled by age, expired is set when connection is older than age.
+type connAge struct {
+	state   http.ConnState
+	expired bool
+	timer   *time.Timer
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package squad
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSquad_RunServerUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "squad.sock")
+	// NOTE: socket file is left by crashed process.
+	stale, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.NoError(t, stale.Close())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(time.Second)))
+	assert.NoError(t, err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, r.Host) })
+	testGroup.RunServer(&http.Server{Addr: "unix://" + path, Handler: handler, ReadHeaderTimeout: time.Second})
+	testGroup.RunServerListener(&http.Server{Handler: handler, ReadHeaderTimeout: time.Second}, ln)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			if addr == "unix:80" {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			}
+			return (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		},
+	}}
+	get := func(url string) string {
+		var body []byte
+		assert.Eventually(t, func() bool {
+			resp, err := client.Get(url)
+			if err != nil {
+				return false
+			}
+			defer resp.Body.Close()
+			body, err = io.ReadAll(resp.Body)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		return string(body)
+	}
+	assert.Equal(t, "unix", get("http://unix"))
+	assert.Equal(t, ln.Addr().String(), get("http://"+ln.Addr().String()))
+	client.CloseIdleConnections()
+	// NOTE: server on given listener is named by its address.
+	assert.Equal(t, "server "+ln.Addr().String(), testGroup.Describe()[1].Name)
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+
+	// NOTE: socket file is removed and given listener is closed during shutdown.
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = ln.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
@@ -0,0 +1,62 @@
+package squad
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ShutdownState describes shutdown of squad for drain-aware decisions.
+type ShutdownState struct {
+	Draining bool
+	// Remaining is time left until squad context is canceled, zero unless squad is draining.
+	Remaining time.Duration
+}
+
+// ShutdownState returns actual shutdown state of squad.
+func (s *Squad) ShutdownState() ShutdownState {
+	if s.ctx.Err() != nil {
+		return ShutdownState{Draining: true}
+	}
+	if s.drainContext().Err() == nil {
+		return ShutdownState{}
+	}
+	return ShutdownState{Draining: true, Remaining: s.drainTimer.Remaining()}
+}
+
+// squadKey is context key of squad, which is carried by contexts of members
+// and requests, see InjectShutdownState.
+type squadKey struct{}
+
+// InjectShutdownState is middleware, which injects squad into request context,
+// so handlers get actual shutdown state by ShutdownStateFrom or Checkpoint.
+func (s *Squad) InjectShutdownState(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), squadKey{}, s)))
+	})
+}
+
+// ShutdownStateFrom returns actual shutdown state of squad injected into ctx
+// by InjectShutdownState, ok is false if ctx does not carry squad.
+func ShutdownStateFrom(ctx context.Context) (state ShutdownState, ok bool) {
+	s, ok := ctx.Value(squadKey{}).(*Squad)
+	if !ok {
+		return ShutdownState{}, false
+	}
+	return s.ShutdownState(), true
+}
+
+// Checkpoint cheaply reports whether shutdown of squad carried by ctx has begun,
+// it is intended for tight CPU-bound loops of members, which yield promptly on
+// ErrShuttingDown without polling ctx.Done() every iteration. For other contexts
+// Checkpoint returns ctx.Err().
+func Checkpoint(ctx context.Context) error {
+	s, ok := ctx.Value(squadKey{}).(*Squad)
+	if !ok {
+		return ctx.Err()
+	}
+	if s.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package squad
+
+import "os"
+
+// defaultSignals are signals handled by signal handler by default.
+var defaultSignals = []os.Signal{os.Interrupt}
+
+// reloadSignals are signals invoking reload functions by default.
+var reloadSignals []os.Signal
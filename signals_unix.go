@@ -0,0 +1,14 @@
+//go:build unix
+
+package squad
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultSignals are signals handled by signal handler by default.
+var defaultSignals = []os.Signal{syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGQUIT}
+
+// reloadSignals are signals invoking reload functions by default.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,142 @@
+//go:build unix
+
+package squad
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSquad_MultipleSquads(t *testing.T) {
+	parent, err := New(WithSignalHandler(WithSignals(syscall.SIGUSR1), WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+	child, err := New(WithManualTrigger(WithTrigger(parent.Draining()), WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+	other, err := New(WithSignalHandler(WithSignals(syscall.SIGUSR2), WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+
+	for _, s := range []*Squad{parent, child, other} {
+		s.Run(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+	}
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.NoError(t, parent.Wait())
+	assert.NoError(t, child.Wait())
+	assert.NoError(t, other.Ready(context.Background()))
+
+	other.Stop(nil)
+	assert.NoError(t, other.Wait())
+}
+
+func TestSquad_SignalPolicy(t *testing.T) {
+	testGroup, err := New(WithSignalHandler(
+		WithSignals(syscall.SIGTERM),
+		WithSignalPolicy(map[os.Signal]SignalAction{
+			syscall.SIGUSR1: SignalIgnore,
+			syscall.SIGUSR2: SignalImmediate,
+		}),
+		WithGracefulPeriod(10*time.Second),
+	))
+	assert.NoError(t, err)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, testGroup.Ready(context.Background()))
+
+	start := time.Now()
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	assert.NoError(t, testGroup.Wait())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSquad_Reloader(t *testing.T) {
+	var reloads atomic.Int32
+	testGroup, err := New(
+		WithReloader(func(context.Context) error {
+			reloads.Add(1)
+			return nil
+		}),
+		WithSignalHandler(
+			WithSignalPolicy(map[os.Signal]SignalAction{syscall.SIGUSR2: SignalReload}),
+			WithShutdownInGracePriod(100*time.Millisecond),
+		),
+	)
+	assert.NoError(t, err)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return reloads.Load() == 1 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, func() bool { return reloads.Load() == 2 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, testGroup.Ready(context.Background()))
+
+	assert.NoError(t, testGroup.Reload(context.Background()))
+	assert.Equal(t, int32(3), reloads.Load())
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_SignalReason(t *testing.T) {
+	testGroup, err := New(WithSignalHandler(WithSignals(syscall.SIGUSR1), WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+
+	causes := make(chan error, 1)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	})
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, ShutdownReason{Kind: ReasonSignal, Signal: syscall.SIGUSR1}, testGroup.ShutdownReason())
+
+	var signalErr *SignalError
+	assert.ErrorAs(t, <-causes, &signalErr)
+	assert.Equal(t, syscall.SIGUSR1, signalErr.Signal)
+}
+
+func TestSquad_SignalAction(t *testing.T) {
+	out := &syncBuffer{}
+	testGroup, err := New(
+		WithLogger(slog.New(slog.NewTextHandler(out, nil))),
+		WithSignalHandler(
+			WithSignals(syscall.SIGTERM),
+			WithSignalAction(syscall.SIGUSR1, SignalDiagnostics),
+			WithShutdownInGracePriod(100*time.Millisecond),
+		),
+	)
+	assert.NoError(t, err)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, WithTaskName("worker"))
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), `msg="member status" name=worker running=true`)
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, testGroup.Ready(context.Background()))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package squad
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultSignals are signals handled by signal handler by default,
+// CTRL_CLOSE_EVENT, CTRL_LOGOFF_EVENT and CTRL_SHUTDOWN_EVENT are delivered as SIGTERM.
+var defaultSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// reloadSignals are signals invoking reload functions by default.
+var reloadSignals []os.Signal
@@ -0,0 +1,70 @@
+package squad
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// inUsePoll is interval of polling in-use connections of sql pool, see WithWaitInUse.
+const inUsePoll = 10 * time.Millisecond
+
+// SQLPoolOpt is an option that can be applied to sql pool subsystem.
+type SQLPoolOpt func(*sqlPool)
+
+// WithWaitInUse makes sql pool subsystem wait for in-use connections to return
+// to pool before closing it, pool is closed anyway after shutdown timeout.
+func WithWaitInUse() SQLPoolOpt {
+	return func(p *sqlPool) {
+		p.waitInUse = true
+	}
+}
+
+// WithSQLPoolName sets name of sql pool subsystem, by default it is "sql".
+func WithSQLPoolName(name string) SQLPoolOpt {
+	return func(p *sqlPool) {
+		p.name = name
+	}
+}
+
+type sqlPool struct {
+	db        *sql.DB
+	name      string
+	waitInUse bool
+}
+
+// WithSQLPool is Squad option that adds database/sql pool as subsystem: database
+// is pinged within pingTimeout during bootstrap, so unreachable database fails
+// squad fast, and pool is closed after other cleanup functions.
+// Ping is also used as health check of subsystem, see Squad.Ready.
+func WithSQLPool(db *sql.DB, pingTimeout time.Duration, opts ...SQLPoolOpt) Option {
+	pool := &sqlPool{db: db, name: "sql"}
+	for _, opt := range opts {
+		opt(pool)
+	}
+
+	ping := func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+		defer cancel()
+		return db.PingContext(ctx)
+	}
+	return WithSubsystem(ping, pool.close, WithSubsystemName(pool.name), WithHealthCheck(ping))
+}
+
+// close closes pool, after in-use connections have been returned if required.
+func (p *sqlPool) close(ctx context.Context) error {
+	if p.waitInUse {
+		ticker := time.NewTicker(inUsePoll)
+		defer ticker.Stop()
+
+		for p.db.Stats().InUse > 0 {
+			select {
+			case <-ctx.Done():
+				return errors.Join(ctx.Err(), p.db.Close())
+			case <-ticker.C:
+			}
+		}
+	}
+	return p.db.Close()
+}
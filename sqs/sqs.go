@@ -0,0 +1,167 @@
+// Package sqs contains adapter of AWS SQS long-poll consumers to squad consumers,
+// without dependency on AWS SDK.
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/moeryomenko/squad"
+)
+
+// defaultVisibilityTimeout is default visibility timeout of SQS queue.
+const defaultVisibilityTimeout = 30 * time.Second
+
+// Client is a client of SQS queue, which is usually thin wrapper of SDK client, e.g.
+//
+//	func (c client) Receive(ctx context.Context, queueURL string) ([]types.Message, error) {
+//		out, err := c.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+//			QueueUrl: &queueURL, MaxNumberOfMessages: 10, WaitTimeSeconds: 20,
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Messages, nil
+//	}
+type Client[M any] interface {
+	// Receive long-polls queue for batch of messages.
+	Receive(ctx context.Context, queueURL string) ([]M, error)
+	// Delete deletes handled message from queue.
+	Delete(ctx context.Context, queueURL string, msg M) error
+	// ChangeVisibility sets visibility timeout of message relative to now.
+	ChangeVisibility(ctx context.Context, queueURL string, msg M, timeout time.Duration) error
+}
+
+// Option is an option that can be applied to consumer.
+type Option func(*options)
+
+type options struct {
+	visibility time.Duration
+	taskOpts   []squad.TaskOption
+}
+
+// WithVisibilityTimeout sets visibility timeout of queue, by default it is 30 seconds.
+// During drain visibility of in-flight messages is extended by this timeout at half of it.
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.visibility = timeout
+	}
+}
+
+// WithTaskOptions sets options of squad member running consumer.
+func WithTaskOptions(opts ...squad.TaskOption) Option {
+	return func(o *options) {
+		o.taskOpts = opts
+	}
+}
+
+// RunConsumer runs long-poll consumer of queue as squad consumer. Messages of received
+// batch are handled concurrently within handle context, handled messages are deleted,
+// while failed ones are left in queue for redelivery after visibility timeout.
+// When squad begins shutdown consumer stops receiving, in-flight messages are handled
+// and their visibility is extended while handlers run. Failure of delete or visibility
+// change fails consumer.
+func RunConsumer[M any](s *squad.Squad, client Client[M], queueURL string, handler func(context.Context, M) error, opts ...Option) {
+	o := options{visibility: defaultVisibilityTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &consumer[M]{client: client, queueURL: queueURL, handler: handler, visibility: o.visibility}
+	s.RunConsumer(func(consumeCtx, handleCtx context.Context) error {
+		receiveCtx, cancel := context.WithCancel(consumeCtx)
+		defer cancel()
+		go func() {
+			select {
+			case <-s.Draining():
+				cancel()
+			case <-receiveCtx.Done():
+			}
+		}()
+
+		for {
+			msgs, err := client.Receive(receiveCtx, queueURL)
+			if receiveCtx.Err() != nil {
+				// NOTE: messages received by canceled poll become visible after timeout.
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := c.handle(handleCtx, s.Draining(), msgs); err != nil {
+				return err
+			}
+		}
+	}, o.taskOpts...)
+}
+
+// consumer handles batches of queue messages.
+type consumer[M any] struct {
+	client     Client[M]
+	queueURL   string
+	handler    func(context.Context, M) error
+	visibility time.Duration
+}
+
+// handle handles batch of messages and extends their visibility during drain.
+func (c *consumer[M]) handle(ctx context.Context, draining <-chan struct{}, msgs []M) error {
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		errs []error
+	)
+	done := make([]chan struct{}, len(msgs))
+	for i, msg := range msgs {
+		i, msg := i, msg
+		done[i] = make(chan struct{})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+			if c.handler(ctx, msg) != nil {
+				return
+			}
+			if err := c.client.Delete(ctx, c.queueURL, msg); err != nil {
+				mtx.Lock()
+				errs = append(errs, err)
+				mtx.Unlock()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := c.extend(ctx, draining, done[i], msg); err != nil {
+				mtx.Lock()
+				errs = append(errs, err)
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// extend extends visibility of message during drain until handling is done.
+func (c *consumer[M]) extend(ctx context.Context, draining, done <-chan struct{}, msg M) error {
+	select {
+	case <-done:
+		return nil
+	case <-draining:
+	}
+
+	for {
+		if err := c.client.ChangeVisibility(ctx, c.queueURL, msg, c.visibility); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(c.visibility / 2)
+		select {
+		case <-done:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
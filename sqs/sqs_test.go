@@ -0,0 +1,96 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+type testClient struct {
+	batches chan []string
+
+	mtx        sync.Mutex
+	deleted    []string
+	extended   map[string]int
+	receiveErr error
+}
+
+func (c *testClient) Receive(ctx context.Context, _ string) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case batch := <-c.batches:
+		return batch, c.receiveErr
+	}
+}
+
+func (c *testClient) Delete(_ context.Context, _, msg string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.deleted = append(c.deleted, msg)
+	return nil
+}
+
+func (c *testClient) ChangeVisibility(_ context.Context, _, msg string, _ time.Duration) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.extended[msg]++
+	return nil
+}
+
+func (c *testClient) snapshot() (deleted []string, extended map[string]int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.deleted, c.extended
+}
+
+func TestRunConsumer(t *testing.T) {
+	s, err := squad.New(squad.WithManualTrigger(
+		squad.WithGracefulPeriod(time.Second),
+		squad.WithShutdownTimeout(500*time.Millisecond),
+	))
+	assert.NoError(t, err)
+
+	client := &testClient{batches: make(chan []string), extended: make(map[string]int)}
+	handling := make(chan struct{})
+	RunConsumer[string](s, client, "queue", func(_ context.Context, msg string) error {
+		switch msg {
+		case "slow":
+			close(handling)
+			time.Sleep(200 * time.Millisecond)
+		case "bad":
+			return errors.New("handle failed")
+		}
+		return nil
+	}, WithVisibilityTimeout(100*time.Millisecond))
+
+	client.batches <- []string{"fast", "bad"}
+	client.batches <- []string{"slow"}
+	<-handling
+	s.Stop(nil)
+
+	assert.NoError(t, s.Wait())
+	deleted, extended := client.snapshot()
+	assert.ElementsMatch(t, []string{"fast", "slow"}, deleted)
+	assert.GreaterOrEqual(t, extended["slow"], 2)
+	assert.Zero(t, extended["fast"])
+}
+
+func TestRunConsumer_Failure(t *testing.T) {
+	errThrottled := errors.New("throttled")
+
+	s, err := squad.New()
+	assert.NoError(t, err)
+
+	client := &testClient{batches: make(chan []string, 1), extended: make(map[string]int), receiveErr: errThrottled}
+	client.batches <- nil
+	RunConsumer[string](s, client, "queue", func(context.Context, string) error { return nil })
+
+	assert.ErrorIs(t, s.Wait(), errThrottled)
+}
@@ -22,13 +22,30 @@
 // limitations under the License.
 
 // Package squad contains a shared shutdown primitive.
+//
+// Squad keeps no global state, so several squads can coexist in one process,
+// each with own lifecycle. Squads with WithManualTrigger are isolated from
+// process signals, and squads can be wired together by triggers, e.g. squad
+// following shutdown of parent squad:
+//
+//	child, err := squad.New(squad.WithManualTrigger(squad.WithTrigger(parent.Draining())))
+//
+// Without WithSignalHandler or WithManualTrigger squad has no graceful period:
+// Squad.Stop, failure of member or cancellation of parent context cancel squad
+// context immediately, servers are drained at the same moment and cleanup functions
+// have default shutdown timeout. Options, which make sense only with graceful period,
+// e.g. WithStandardOrdering, fail New with ErrShutdownHandlerRequired.
 package squad
 
 import (
 	"context"
 	"errors"
-	"net/http"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/moeryomenko/synx"
@@ -45,135 +62,446 @@ const (
 // If one goroutine exits, other goroutines also go down.
 type Squad struct {
 	// primitives for control running goroutines.
-	wg                 *synx.CtxGroup
+	running, jobs      sync.WaitGroup
+	runUntilComplete   bool
+	parent             context.Context
 	ctx, serverContext context.Context
-	cancel             func()
+	cancel, drain      func()
+	cancelCause        context.CancelCauseFunc
+	canceledAt         atomic.Int64
+	drainedAt          atomic.Int64
+	shutdownAt         atomic.Int64
+	drainTimer         DrainTimer
+	preShutdownDelay   time.Duration
+	unready            atomic.Bool
+	shuttingDown       atomic.Bool
+	tasks              atomic.Int64
+	panicPolicy        PanicPolicy
+	panicHandler       func(value any, stack []byte)
+	lowOverhead        bool
+	logger             *slog.Logger
+	restartLogInterval time.Duration
 	funcs              []func(ctx context.Context) error
 
+	// configuration collected by options.
+	shutdownConfig *shutdown
+	conflicts      []error
+
 	// primitives for control goroutines shutdowning.
+	gracefulPeriod    atomic.Int64
 	cancellationDelay time.Duration
-	cancellationFuncs []func(ctx context.Context) error
+	immediateCancel   bool
+	cancellationFuncs []*cleanup
+	cleanupBackoff    time.Duration
+	phases            []shutdownPhase
+	shutdownOnce      sync.Once
+	shutdownErr       error
+	waitOnce          sync.Once
+	waitErr           error
+
+	// bootstrap functions, plain ones run before stages.
+	startupTimeout  time.Duration
+	deferBootstrap  bool
+	earlyBinding    bool
+	inheritSockets  bool
+	bootstrapPolicy BootstrapFailurePolicy
+	bootstrapped    chan struct{}
+	bootstraps      []func(context.Context) error
+	reloaders       []func(context.Context) error
+	stages          []bootstrapStage
+	// subsystems with dependencies in topological order.
+	dependents []*dependent
+
+	// subsystems with health checks, guarded by mtx.
+	subsystems     []*subsystem
+	healthEndpoint *healthEndpoint
+	warmup         *warmup
+	warmupUntil    atomic.Int64
+	warmed         atomic.Bool
 
-	// bootstrap functions.
-	bootstraps []func(context.Context) error
+	// members queued until bootstrap has been completed.
+	launchMtx sync.Mutex
+	launched  bool
+	pending   []func()
 
-	// guarded errors.
-	mtx sync.Mutex
-	err error
+	// members work, which is waited before cleanup: stop timeouts and checkpoints.
+	stopping          sync.WaitGroup
+	checkpointHandler func(ctx context.Context, task string, payload []byte) error
+
+	// standard ordering of shutdown: draining servers and stopping consumers.
+	standardOrdering bool
+	servers          sync.WaitGroup
+	serversDrained   context.Context
+	stopConsumers    func()
+	// hostLock is path of file locked by shutdown, see WithHostLock.
+	hostLock string
+	// notifySocket is socket of systemd notifications, see WithSystemdNotify.
+	notifySocket string
+
+	// lifecycle state of squad, see State, and hooks of its edges.
+	lifecycle lifecycle
+	hooks     []Hooks
+	// hooksDone is closed after OnShutdownStart hooks have returned.
+	hooksDone chan struct{}
+	forceExit forceExit
+
+	// detached functions, which are not squad members,
+	// but squad waits them during shutdown.
+	detached sync.WaitGroup
+
+	// guarded errors, members liveness, reason of stop, bootstrap failure,
+	// reserved fraction of shutdown timeout, open listeners, exit callbacks,
+	// failure of early binding, drain phases of subsystems and inherited listeners.
+	mtx            sync.Mutex
+	errs           []error
+	members        []*liveness
+	cause          error
+	shutdownReason ShutdownReason
+	reserved       float64
+	bootstrapErr   error
+	listeners      map[net.Listener]struct{}
+	exitHooks      []func(Report)
+	bindErr        error
+	drainPhases    map[string]DrainPhase
+	inherited      []net.Listener
 }
 
 // New returns a new Squad with the context.
 func New(opts ...Option) (*Squad, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	squad := &Squad{
-		ctx:               ctx,
-		cancel:            cancel,
-		cancellationDelay: defaultCancellationDelay,
-		wg:                synx.NewCtxGroup(ctx),
-	}
-
+	squad := &Squad{cancellationDelay: defaultCancellationDelay, parent: context.Background()}
 	for _, opt := range opts {
 		opt(squad)
 	}
 
-	if err := onStart(ctx, squad.bootstraps...); err != nil {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(squad.parent))
+	squad.ctx = context.WithValue(ctx, squadKey{}, squad)
+	squad.cancelCause = func(cause error) {
+		now := time.Now().UnixNano()
+		squad.shutdownAt.CompareAndSwap(0, now)
+		squad.setState(StateStopping)
+		squad.shuttingDown.Store(true)
+		squad.canceledAt.CompareAndSwap(0, now)
+		cancel(cause)
+	}
+	squad.cancel = func() { squad.cancelCause(nil) }
+	squad.drainTimer.s, squad.drainTimer.changed = squad, make(chan struct{}, 1)
+	squad.hooksDone = make(chan struct{})
+
+	if err := squad.setup(); err != nil {
+		squad.abort()
 		return nil, err
 	}
 
+	if !squad.deferBootstrap {
+		if err := squad.launch(ctx); err != nil {
+			if err = squad.bootstrapFailed(ctx, err); err != nil {
+				squad.abort()
+				return nil, err
+			}
+		}
+	}
+
 	for _, f := range squad.funcs {
 		squad.Run(f)
 	}
+	squad.created()
 
 	return squad, nil
 }
 
-// RunServer is wrapper function for launch http server.
-func (s *Squad) RunServer(srv *http.Server) {
-	s.wg.Go(func(_ context.Context) error {
-		err := srv.ListenAndServe()
-		if err == nil || errors.Is(err, http.ErrServerClosed) {
-			return nil
-		}
-		return err
-	})
-
-	// NOTE: After receiving shutdowning signal first of all,
-	// gracefully shuts down the server without interrupting any active connections.
-	go func(ctx context.Context) {
-		shutdownCtx := context.WithoutCancel(ctx)
-		<-ctx.Done()
-		err := srv.Shutdown(shutdownCtx)
-		s.appendErr(err)
-	}(s.serverContext)
+// abort releases squad, New of which has failed: health endpoint
+// and listeners bound by setup or bootstrap are closed.
+func (s *Squad) abort() {
+	s.cancel()
+	s.disarmForceExit()
+	_ = s.closeListeners()
+	s.closeHealth()
 }
 
 // RunConsumer is wrapper function for run cosumer worker
 // after receiving shutdowning signal stop context for consumer events/messages
-// without interrupting any active handler.
-func (s *Squad) RunConsumer(consumer ConsumerLoop) {
-	s.wg.Go(func(ctx context.Context) error {
-		return consumer(ctx, context.WithoutCancel(ctx))
+// without interrupting any active handler, see WithDrainStrategy
+// for other ways to deal with fetched events/messages.
+func (s *Squad) RunConsumer(consumer ConsumerLoop, opts ...TaskOption) {
+	t := s.newTask(opts)
+	t.background = true
+
+	s.goTask(t, func(ctx context.Context) error {
+		consumeCtx, cancel := s.consumeContext(ctx)
+		defer cancel()
+		handleCtx := context.WithValue(context.WithoutCancel(ctx), consumerKey{}, &consumerDrain{
+			consumeCtx: consumeCtx,
+			strategy:   t.drainStrategy,
+		})
+		return consumer(consumeCtx, handleCtx)
 	})
 }
 
-// Run runs the fn. When fn is done, it signals all the group members to stop.
-func (s *Squad) Run(fn func(context.Context) error) {
-	s.RunGracefully(fn, nil)
+// Run runs the fn. When fn is failed, it signals all the group members to stop.
+func (s *Squad) Run(fn func(context.Context) error, opts ...TaskOption) {
+	s.RunGracefully(fn, nil, opts...)
 }
 
-// RunGracefully runs the backgroudFn. When fn is done, it signals all group members to stop.
+// RunGracefully runs the backgroudFn. When fn is failed, it signals all group members to stop.
 // When stop signal has been received, squad run onDown function.
-func (s *Squad) RunGracefully(backgroudFn, onDown func(context.Context) error) {
-	if onDown != nil {
-		s.cancellationFuncs = append(s.cancellationFuncs, onDown)
+func (s *Squad) RunGracefully(backgroudFn, onDown func(context.Context) error, opts ...TaskOption) {
+	t := s.newTask(opts)
+	s.addCleanup(tierFlush, "", onDown).timeout = t.cleanupTimeout
+
+	s.goTask(t, backgroudFn)
+}
+
+// RunAndForget runs the fn detached from squad members: fn's completion
+// does not stop squad and its error is passed to onErr instead of squad errors.
+// Squad waits detached functions during shutdown within cancellation delay.
+// Panic of fn is passed to onErr as PanicError and handled by default panic
+// policy of squad, see WithDefaultPanicPolicy.
+func (s *Squad) RunAndForget(fn func(context.Context) error, onErr func(error)) {
+	s.detached.Add(1)
+
+	go func() {
+		defer s.detached.Done()
+
+		err := s.runDetached(fn)
+		if err != nil && onErr != nil {
+			onErr(err)
+		}
+	}()
+}
+
+// runDetached runs detached function applying default panic policy of squad.
+func (s *Squad) runDetached(fn func(context.Context) error) error {
+	for {
+		err := s.guarded(s.ctx, "detached", phaseRun, fn)
+
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			return err
+		}
+
+		switch s.panicPolicy {
+		case PanicRestart:
+			if waitBackoff(s.ctx, panicRestartDelay) {
+				continue
+			}
+		case PanicIsolate:
+		default:
+			s.Stop(err)
+		}
+		return err
 	}
+}
 
-	s.wg.Go(backgroudFn)
+// Stop begins squad shutdown same as receiving signal by signal handler.
+// Without signal handler or manual trigger squad context is canceled immediately.
+// Non-nil reason is returned by Wait and set as cause of squad context cancellation,
+// e.g. when service terminates itself on fatal condition.
+func (s *Squad) Stop(reason error) {
+	s.appendErr(reason)
+	s.beginShutdown(ShutdownReason{Kind: ReasonStopped, Err: reason}, reason)
 }
 
-// Wait blocks until all squad members exit.
+// beginShutdown begins graceful shutdown by given reason.
+func (s *Squad) beginShutdown(reason ShutdownReason, cause error) {
+	s.setReason(reason, cause)
+	s.shutdownAt.CompareAndSwap(0, time.Now().UnixNano())
+
+	switch {
+	case s.drain == nil:
+		s.cancelCause(s.causeOf())
+	case s.preShutdownDelay > 0:
+		if s.unready.CompareAndSwap(false, true) {
+			s.setState(StateDraining)
+			s.log(slog.LevelInfo, "pre-shutdown delay started", "delay", s.preShutdownDelay, "reason", cause)
+			time.AfterFunc(s.preShutdownDelay, func() { s.startDrain(cause) })
+		}
+	default:
+		s.startDrain(cause)
+	}
+}
+
+// startDrain begins graceful period: servers are drained and after delay squad context is canceled.
+func (s *Squad) startDrain(cause error) {
+	now := time.Now().UnixNano()
+	s.shutdownAt.CompareAndSwap(0, now)
+	s.setState(StateDraining)
+	s.shuttingDown.Store(true)
+	if s.drainedAt.CompareAndSwap(0, now) {
+		s.log(slog.LevelInfo, "graceful period started",
+			"graceful_period", s.GracefulPeriod(), "shutdown_timeout", s.cancellationDelay, "reason", cause)
+		s.notifySystemd("STOPPING=1")
+	}
+	s.drainTimer.start(s.delay())
+	s.drain()
+}
+
+// Context returns squad context, which members are run with, e.g. for tasks started
+// by other frameworks. It is canceled after graceful period, see Squad.Run.
+func (s *Squad) Context() context.Context {
+	return s.ctx
+}
+
+// ShutdownContext returns context, which is done as soon as squad begins shutdown,
+// before squad context is canceled, see Squad.Draining.
+func (s *Squad) ShutdownContext() context.Context {
+	return s.drainContext()
+}
+
+// GracefulPeriod returns graceful period of squad shutdown,
+// it is zero without signal handler or manual trigger.
+func (s *Squad) GracefulPeriod() time.Duration {
+	return time.Duration(s.gracefulPeriod.Load())
+}
+
+// ShutdownTimeout returns time reserved for cleanup functions.
+func (s *Squad) ShutdownTimeout() time.Duration {
+	return s.cancellationDelay
+}
+
+// HardDeadline returns maximum time from beginning of shutdown including pre-shutdown
+// delay until cleanup functions are abandoned.
+func (s *Squad) HardDeadline() time.Duration {
+	return s.preShutdownDelay + max(s.delay(), 0) + s.cancellationDelay
+}
+
+// hardDeadline returns time, when cleanup functions are abandoned,
+// it is counted since now, if shutdown has not begun.
+func (s *Squad) hardDeadline() time.Time {
+	shutdownAt := time.Now()
+	if nanos := s.shutdownAt.Load(); nanos != 0 {
+		shutdownAt = time.Unix(0, nanos)
+	}
+	return shutdownAt.Add(s.HardDeadline())
+}
+
+// Wait blocks until all squad members exit and squad shuts down,
+// subsequent and concurrent calls return result of first one.
 func (s *Squad) Wait() error {
-	err := s.wg.Wait()
-	if err != nil {
-		s.err = errors.Join(s.err, err)
+	s.waitOnce.Do(func() {
+		s.waitErr = s.wait()
+	})
+	return s.waitErr
+}
+
+func (s *Squad) wait() error {
+	if err := s.launchDeferred(); err != nil {
+		s.appendErr(err)
+	} else {
+		s.appendErr(s.waitBootstrap())
+		s.stopOnComplete()
+		err = s.waitMembers()
+		// NOTE: squad context is canceled by shutdown, it is not an error.
+		if err != nil && err != context.Canceled { //nolint:errorlint // compare with ctx.Err() of squad.
+			s.appendErr(err)
+		}
 	}
-	err = s.shutdown()
-	if err != nil {
-		s.err = errors.Join(s.err, err)
+	// NOTE: all members are down, so notify detached functions.
+	s.setReason(ShutdownReason{Kind: ReasonCompleted}, nil)
+	s.cancel()
+	s.stopping.Wait()
+	s.appendErr(s.shutdown())
+	s.waitShutdownHooks()
+	s.closeHealth()
+	s.runExitHooks()
+
+	err := errors.Join(s.Errors()...)
+	s.setState(StateStopped)
+	s.log(slog.LevelInfo, "squad stopped", "error", err)
+	return err
+}
+
+// waitMembers blocks until all members exit or squad context is done.
+func (s *Squad) waitMembers() error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case <-waitDone(&s.running):
+		return nil
 	}
-	return s.err
+}
+
+// Errors returns snapshot of errors accumulated by squad so far,
+// it is safe to call while squad is running.
+func (s *Squad) Errors() []error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return slices.Clone(s.errs)
+}
+
+// drainContext returns context, which is done when squad begins shutdown.
+func (s *Squad) drainContext() context.Context {
+	if s.serverContext != nil {
+		return s.serverContext
+	}
+	return s.ctx
 }
 
 func (s *Squad) appendErr(err error) {
+	if err == nil {
+		return
+	}
+
 	s.mtx.Lock()
-	s.err = errors.Join(s.err, err)
+	s.errs = append(s.errs, err)
 	s.mtx.Unlock()
 }
 
-func (s *Squad) shutdown() error {
-	if len(s.cancellationFuncs) == 0 {
-		return nil
+// delay returns time between receiving shutdown signal and cancellation squad context.
+func (s *Squad) delay() time.Duration {
+	if s.immediateCancel {
+		return 0
 	}
+	return time.Duration(s.gracefulPeriod.Load()) - s.cancellationDelay
+}
+
+// shutdown runs cleanup functions once, subsequent calls return result of first run.
+func (s *Squad) shutdown() error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = joinErrors(s.closeListeners(), s.withHostLock(s.runCleanups))
+	})
+	return s.shutdownErr
+}
 
-	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), s.cancellationDelay)
+func (s *Squad) runCleanups() error {
+	ctx, end := s.traceTask(context.WithoutCancel(s.ctx), "squad.shutdown")
+	defer end()
+
+	ctx, cancel := context.WithDeadline(ctx, s.cleanupDeadline())
 	defer cancel()
 
 	group := synx.NewErrGroup(ctx)
-	for _, cancelFn := range s.cancellationFuncs {
-		cancelFn := cancelFn
-		group.Go(func(ctx context.Context) error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case err := <-callTimeout(ctx, cancelFn):
-				return err
-			}
-		})
-	}
+	group.Go(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-waitDone(&s.detached):
+			return nil
+		}
+	})
+	group.Go(func(ctx context.Context) error {
+		// NOTE: servers may be still draining, if squad has been
+		// stopped by failure of member, so wait them before cleanups.
+		select {
+		case <-ctx.Done():
+		case <-waitDone(&s.servers):
+		}
+		return s.runCleanupTiers(ctx)
+	})
 
 	return group.Wait()
 }
 
+// cleanupDeadline returns deadline of cleanup functions: shutdown timeout
+// is reserved since cancellation of squad context, so time consumed
+// by stopping members is not available for cleanup, but no later than hard deadline.
+func (s *Squad) cleanupDeadline() time.Time {
+	canceledAt := time.Now()
+	if nanos := s.canceledAt.Load(); nanos != 0 {
+		canceledAt = time.Unix(0, nanos)
+	}
+	return s.drainTimer.limit(canceledAt.Add(s.cancellationDelay))
+}
+
 func callTimeout(ctx context.Context, fn func(context.Context) error) chan error {
 	ch := make(chan error, 1)
 
@@ -184,14 +512,179 @@ func callTimeout(ctx context.Context, fn func(context.Context) error) chan error
 	return ch
 }
 
-func onStart(ctx context.Context, bootstraps ...func(context.Context) error) error {
+func waitDone(wg *sync.WaitGroup) chan struct{} {
+	ch := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	return ch
+}
+
+// ErrConflictingOptions is returned by New, when given options contradict each other.
+var ErrConflictingOptions = errors.New("conflicting options")
+
+// ErrInvalidOption is returned by New, when option has invalid argument.
+var ErrInvalidOption = errors.New("invalid option")
+
+// ErrShutdownHandlerRequired is returned by New, when option requires
+// WithSignalHandler or WithManualTrigger.
+var ErrShutdownHandlerRequired = errors.New("option requires signal handler or manual trigger")
+
+// setup builds squad from configuration collected by options,
+// so result does not depend on order of options.
+func (s *Squad) setup() error {
+	if err := errors.Join(s.conflicts...); err != nil {
+		return err
+	}
+	if s.standardOrdering && s.shutdownConfig == nil {
+		return fmt.Errorf("%w: WithStandardOrdering", ErrShutdownHandlerRequired)
+	}
+	sorted, err := sortDependents(s.dependents)
+	if err != nil {
+		return err
+	}
+	s.dependents = sorted
+
+	if config := s.shutdownConfig; config != nil {
+		s.setupShutdown(*config)
+	}
+	if s.inheritSockets {
+		if err := s.inheritListeners(); err != nil {
+			return err
+		}
+	}
+	s.setupSignals()
+	s.watchParent()
+	if s.healthEndpoint != nil {
+		return s.serveHealth(s.healthEndpoint)
+	}
+	return nil
+}
+
+func (s *Squad) conflict(option, other string) {
+	s.conflicts = append(s.conflicts, fmt.Errorf("%w: %s and %s", ErrConflictingOptions, option, other))
+}
+
+// launch runs bootstrap functions and then launches queued members.
+func (s *Squad) launch(ctx context.Context) error {
+	s.setState(StateBootstrapping)
+	if err := s.start(ctx); err != nil {
+		return err
+	}
+
+	s.launchMtx.Lock()
+	pending := s.pending
+	s.pending, s.launched = nil, true
+	s.launchMtx.Unlock()
+	s.startWarmup()
+	s.setState(StateRunning)
+	s.log(slog.LevelInfo, "squad started")
+	s.notifySystemd("READY=1")
+
+	for _, run := range pending {
+		run()
+	}
+	return nil
+}
+
+// launchDeferred runs deferred bootstrap, which is interrupted when squad begins shutdown.
+func (s *Squad) launchDeferred() error {
+	if !s.deferBootstrap {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	defer context.AfterFunc(s.drainContext(), cancel)()
+
+	err := s.launch(ctx)
+	if err != nil && s.drainContext().Err() != nil {
+		return fmt.Errorf("%w: %w", ErrShuttingDown, err)
+	}
+	return err
+}
+
+// enqueue queues launching of member until bootstrap has been completed,
+// it reports whether member has been queued.
+func (s *Squad) enqueue(run func()) bool {
+	s.launchMtx.Lock()
+	defer s.launchMtx.Unlock()
+
+	if s.launched {
+		return false
+	}
+	s.pending = append(s.pending, run)
+	return true
+}
+
+// ErrBootstrapTimeout is returned by New, when bootstrap functions exceed startup timeout.
+var ErrBootstrapTimeout = errors.New("bootstrap timeout")
+
+// start runs bootstrap functions bounded by startup timeout.
+func (s *Squad) start(ctx context.Context) error {
+	if s.startupTimeout <= 0 {
+		return s.bootstrap(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.startupTimeout)
+	defer cancel()
+
+	select {
+	case err := <-callTimeout(ctx, s.bootstrap):
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("%w: %w", ErrBootstrapTimeout, err)
+		}
+		return err
+	case <-ctx.Done():
+		return ErrBootstrapTimeout
+	}
+}
+
+// bootstrapStage is a group of bootstrap functions,
+// which run concurrently after previous stage has been completed.
+type bootstrapStage struct {
+	name string
+	fns  []func(context.Context) error
+}
+
+func (s *Squad) bootstrap(ctx context.Context) error {
+	ctx, end := s.traceTask(ctx, "squad.bootstrap")
+	defer end()
+
+	if err := s.bindFailed(); err != nil {
+		return err
+	}
+
+	if err := s.onStart(ctx, phaseBootstrap, s.bootstraps...); err != nil {
+		return err
+	}
+
+	for _, stage := range s.stages {
+		err := s.labeled(ctx, stage.name, phaseStage, func(ctx context.Context) error {
+			return s.onStart(ctx, stage.name, stage.fns...)
+		})
+		if err != nil {
+			return fmt.Errorf("bootstrap stage %s: %w", stage.name, err)
+		}
+	}
+
+	return s.initDependents(ctx)
+}
+
+func (s *Squad) onStart(ctx context.Context, stage string, bootstraps ...func(context.Context) error) error {
 	if len(bootstraps) == 0 {
 		return nil
 	}
 
 	group := synx.NewErrGroup(ctx)
 	for _, fn := range bootstraps {
-		group.Go(fn)
+		fn := fn
+		group.Go(func(ctx context.Context) error {
+			return s.labeled(ctx, stage, phaseBootstrap, fn)
+		})
 	}
 
 	return group.Wait()
@@ -1,8 +1,34 @@
 package squad
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -122,3 +148,2806 @@ func TestSquad(t *testing.T) {
 		})
 	}
 }
+
+func TestSquad_RunAndForget(t *testing.T) {
+	errDetached := errors.New("detached failed")
+
+	testGroup, err := New(WithSignalHandler(WithShutdownTimeout(time.Second)))
+	assert.NoError(t, err)
+
+	errs := make(chan error, 1)
+	testGroup.RunAndForget(func(ctx context.Context) error {
+		<-ctx.Done()
+		<-time.After(100 * time.Millisecond)
+		return errDetached
+	}, func(err error) { errs <- err })
+
+	testGroup.Run(func(ctx context.Context) error { return nil })
+
+	assert.NoError(t, testGroup.Wait())
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, errDetached)
+	default:
+		t.Fatal("squad did not wait detached function")
+	}
+}
+
+func TestSquad_GracePeriodFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grace")
+	assert.NoError(t, os.WriteFile(path, []byte("10"), 0o600))
+
+	testGroup, err := New(WithSignalHandler(
+		WithShutdownTimeout(time.Second),
+		WithGracePeriodFile(path, 10*time.Millisecond),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, 9*time.Second, testGroup.delay())
+
+	assert.NoError(t, os.WriteFile(path, []byte("5s"), 0o600))
+	assert.Eventually(t, func() bool {
+		return testGroup.delay() == 4*time.Second
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+	<-time.After(50 * time.Millisecond)
+	assert.Equal(t, 4*time.Second, testGroup.delay())
+
+	testGroup.Run(func(ctx context.Context) error { return nil })
+	assert.NoError(t, testGroup.Wait())
+
+	_, err = New(WithSignalHandler(WithGracePeriodFile(path, 0)))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	assert.EqualError(t, err, "invalid option: WithSignalHandler(WithGracePeriodFile) interval must be positive, got 0s")
+}
+
+func TestSquad_ImmediateCancel(t *testing.T) {
+	testGroup, err := New(WithSignalHandler(WithImmediateCancel()))
+	assert.NoError(t, err)
+	assert.Zero(t, testGroup.delay())
+	assert.Equal(t, defaultCancellationDelay, testGroup.cancellationDelay)
+}
+
+func TestSquad_ManualTrigger(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	srv := &http.Server{Addr: "127.0.0.1:0", ReadHeaderTimeout: time.Second}
+	testGroup.RunServer(srv)
+
+	stopped := make(chan struct{})
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+
+	time.AfterFunc(50*time.Millisecond, func() { testGroup.Stop(nil) })
+
+	assert.NoError(t, testGroup.Wait())
+	<-stopped
+	assert.ErrorIs(t, srv.ListenAndServe(), http.ErrServerClosed)
+}
+
+func TestSquad_Trigger(t *testing.T) {
+	trigger := make(chan struct{})
+	testGroup, err := New(WithSignalHandler(
+		WithShutdownInGracePriod(100*time.Millisecond),
+		WithTrigger(trigger),
+	))
+	assert.NoError(t, err)
+
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	close(trigger)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_BootstrapStages(t *testing.T) {
+	errWarmup := errors.New("warmup failed")
+
+	var (
+		mtx   sync.Mutex
+		order []string
+	)
+	step := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			mtx.Lock()
+			order = append(order, name)
+			mtx.Unlock()
+			return err
+		}
+	}
+
+	_, err := New(
+		WithBootstrapStage("migrate", step("migrate", nil)),
+		WithBootstrapStage("pools", step("db", nil), step("cache", nil)),
+		WithBootstrapStage("warmup", step("warmup", errWarmup)),
+		WithBootstrapStage("register", step("register", nil)),
+	)
+	assert.ErrorIs(t, err, errWarmup)
+	assert.EqualError(t, err, "bootstrap stage warmup: warmup failed")
+	assert.Equal(t, "migrate", order[0])
+	assert.ElementsMatch(t, []string{"db", "cache"}, order[1:3])
+	assert.Equal(t, []string{"warmup"}, order[3:])
+}
+
+func TestSquad_LazySubsystem(t *testing.T) {
+	var inits, closes int
+	used := Lazy(
+		func(context.Context) error { inits++; return nil },
+		func(context.Context) error { closes++; return nil },
+	)
+	unused := Lazy(
+		func(context.Context) error { panic("must not be initialized") },
+		func(context.Context) error { panic("must not be closed") },
+	)
+
+	testGroup, err := New(WithLazySubsystem(used, unused))
+	assert.NoError(t, err)
+	assert.Zero(t, inits)
+
+	testGroup.Run(func(ctx context.Context) error {
+		assert.NoError(t, used.Init(ctx))
+		return used.Init(ctx)
+	})
+
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, 1, inits)
+	assert.Equal(t, 1, closes)
+	assert.ErrorIs(t, unused.Init(context.Background()), ErrSubsystemClosed)
+}
+
+func TestSquad_NamedSubsystem(t *testing.T) {
+	errUnhealthy := errors.New("connection lost")
+	errClose := errors.New("close failed")
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+
+	testGroup, err := New(
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithSubsystem(
+			func(context.Context) error { return nil },
+			func(context.Context) error { return errClose },
+			WithSubsystemName("postgres"),
+			WithHealthCheck(func(context.Context) error {
+				if healthy.Load() {
+					return nil
+				}
+				return errUnhealthy
+			}),
+		),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, testGroup.Ready(context.Background()))
+
+	healthy.Store(false)
+	err = testGroup.Ready(context.Background())
+	assert.ErrorIs(t, err, errUnhealthy)
+	assert.EqualError(t, err, "subsystem postgres: connection lost")
+
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, testGroup.Ready(context.Background()), ErrShuttingDown)
+	assert.EqualError(t, testGroup.Wait(), "subsystem postgres: close failed")
+}
+
+func TestSquad_CleanupOnce(t *testing.T) {
+	var closes atomic.Int32
+	testGroup, err := New(
+		WithSubsystem(nil, func(context.Context) error {
+			closes.Add(1)
+			return nil
+		}, WithSubsystemName("cache")),
+	)
+	assert.NoError(t, err)
+	assert.False(t, testGroup.Report().Cleanups[0].Ran)
+
+	testGroup.Run(func(ctx context.Context) error { return nil })
+
+	assert.NoError(t, testGroup.Wait())
+	assert.NoError(t, testGroup.shutdown())
+	assert.Equal(t, int32(1), closes.Load())
+
+	report := testGroup.Report()
+	assert.Len(t, report.Cleanups, 1)
+	assert.Equal(t, "cache", report.Cleanups[0].Name)
+	assert.True(t, report.Cleanups[0].Ran)
+	assert.NoError(t, report.Cleanups[0].Err)
+}
+
+func TestSquad_CleanupRetry(t *testing.T) {
+	errUnavailable := errors.New("service unavailable")
+
+	var calls atomic.Int32
+	testGroup, err := New(
+		WithSignalHandler(WithShutdownTimeout(time.Second)),
+		WithCleanupRetry(10*time.Millisecond),
+		WithCloses(func(context.Context) error {
+			if calls.Add(1) < 3 {
+				return errUnavailable
+			}
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Run(func(ctx context.Context) error { return nil })
+
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, 3, testGroup.Report().Cleanups[0].Attempts)
+}
+
+func TestSquad_Budgets(t *testing.T) {
+	testGroup, err := New(WithSignalHandler(WithShutdownTimeout(5 * time.Second)))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultContextGracePeriod, testGroup.GracefulPeriod())
+	assert.Equal(t, 5*time.Second, testGroup.ShutdownTimeout())
+	assert.Equal(t, defaultContextGracePeriod, testGroup.HardDeadline())
+
+	testGroup, err = New(WithSignalHandler(WithShutdownTimeout(5*time.Second), WithImmediateCancel()))
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, testGroup.HardDeadline())
+}
+
+func TestSquad_CancelCause(t *testing.T) {
+	errTask := errors.New("failed task")
+
+	testGroup, err := New()
+	assert.NoError(t, err)
+
+	causes := make(chan error, 1)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	})
+	testGroup.Run(func(context.Context) error { return errTask }, WithTaskName("worker"))
+
+	assert.EqualError(t, testGroup.Wait(), errTask.Error())
+
+	cause := <-causes
+	var taskErr *TaskError
+	assert.ErrorAs(t, cause, &taskErr)
+	assert.Equal(t, "worker", taskErr.Name)
+	assert.ErrorIs(t, cause, errTask)
+	assert.EqualError(t, cause, "task worker failed: failed task")
+}
+
+func TestSquad_ServerStopsAccept(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	testGroup.RunServerWithOptions(&http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: time.Second,
+		Handler:           http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+	}, ServerOptions{GracePeriod: 300 * time.Millisecond})
+
+	var conn net.Conn
+	assert.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", addr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	request := func() (*http.Response, error) {
+		if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: squad\r\n\r\n"); err != nil {
+			return nil, err
+		}
+		return http.ReadResponse(reader, nil)
+	}
+
+	resp, err := request()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testGroup.Stop(nil)
+	assert.Eventually(t, func() bool {
+		_, err := net.Dial("tcp", addr)
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err = request()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_ProfilerLabels(t *testing.T) {
+	testGroup, err := New(WithCloses(func(ctx context.Context) error {
+		phase, _ := pprof.Label(ctx, "squad.phase")
+		assert.Equal(t, "cleanup", phase)
+		return nil
+	}))
+	assert.NoError(t, err)
+
+	testGroup.Run(func(ctx context.Context) error {
+		member, _ := pprof.Label(ctx, "squad.member")
+		phase, _ := pprof.Label(ctx, "squad.phase")
+		assert.Equal(t, "worker", member)
+		assert.Equal(t, "run", phase)
+		return nil
+	}, WithTaskName("worker"))
+
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_StopTimeout(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(time.Second)))
+	assert.NoError(t, err)
+
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		<-time.After(10 * time.Millisecond)
+		return nil
+	}, WithTaskName("fast"), WithStopTimeout(time.Second))
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		<-time.After(time.Second)
+		return nil
+	}, WithTaskName("stuck"), WithStopTimeout(50*time.Millisecond))
+
+	testGroup.Stop(nil)
+	err = testGroup.Wait()
+	assert.ErrorIs(t, err, ErrTaskHung)
+	assert.EqualError(t, err, "task stuck failed: task did not stop in time")
+}
+
+func TestSquad_StartupTimeout(t *testing.T) {
+	_, err := New(
+		WithStartupTimeout(50*time.Millisecond),
+		WithBootstrap(func(context.Context) error {
+			<-time.After(time.Second)
+			return nil
+		}),
+	)
+	assert.ErrorIs(t, err, ErrBootstrapTimeout)
+
+	_, err = New(
+		WithStartupTimeout(50*time.Millisecond),
+		WithBootstrap(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+	assert.ErrorIs(t, err, ErrBootstrapTimeout)
+}
+
+func TestSquad_DeferredBootstrap(t *testing.T) {
+	var closed, ran atomic.Bool
+	testGroup, err := New(
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithDeferredBootstrap(),
+		WithSubsystem(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, func(context.Context) error {
+			closed.Store(true)
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Run(func(context.Context) error {
+		ran.Store(true)
+		return nil
+	})
+
+	time.AfterFunc(50*time.Millisecond, func() { testGroup.Stop(nil) })
+
+	err = testGroup.Wait()
+	assert.ErrorIs(t, err, ErrShuttingDown)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, closed.Load())
+	assert.False(t, ran.Load())
+}
+
+type testShutdowner struct {
+	stop chan struct{}
+}
+
+func (s testShutdowner) Start(context.Context) error {
+	<-s.stop
+	return nil
+}
+
+func (s testShutdowner) Shutdown(context.Context) error {
+	close(s.stop)
+	return nil
+}
+
+func (s testShutdowner) Stop(ctx context.Context) error {
+	return s.Shutdown(ctx)
+}
+
+func TestSquad_RunShutdowner(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	srv := testShutdowner{stop: make(chan struct{})}
+	testGroup.RunShutdowner(srv.Start, srv, WithTaskName("custom"))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	<-srv.stop
+}
+
+// testService is started at once and keeps running until it is stopped.
+type testService struct {
+	startErr error
+	stopped  chan struct{}
+}
+
+func (s *testService) Start(context.Context) error {
+	return s.startErr
+}
+
+func (s *testService) Stop(context.Context) error {
+	close(s.stopped)
+	return nil
+}
+
+func TestSquad_RunService(t *testing.T) {
+	errStart := errors.New("port is busy")
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	// NOTE: Start of blocking service returns after it has been stopped.
+	blocking := testShutdowner{stop: make(chan struct{})}
+	testGroup.RunService(blocking, WithTaskName("blocking"))
+	nonblocking := &testService{stopped: make(chan struct{})}
+	testGroup.RunService(nonblocking, WithTaskName("nonblocking"))
+
+	failed := &testService{startErr: errStart, stopped: make(chan struct{})}
+	testGroup.RunService(failed, WithTaskName("failed"))
+
+	assert.ErrorIs(t, testGroup.Wait(), errStart)
+	<-blocking.stop
+	<-nonblocking.stopped
+	<-failed.stopped
+}
+
+type testHTTP3Server struct {
+	testShutdowner
+	closed atomic.Bool
+}
+
+func (s *testHTTP3Server) ListenAndServe() error {
+	<-s.stop
+	return http.ErrServerClosed
+}
+
+func (s *testHTTP3Server) Shutdown(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *testHTTP3Server) Close() error {
+	s.closed.Store(true)
+	close(s.stop)
+	return nil
+}
+
+func (*testHTTP3Server) SetQUICHeaders(hdr http.Header) error {
+	hdr.Set("Alt-Svc", `h3=":443"; ma=2592000`)
+	return nil
+}
+
+func TestSquad_Context(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+
+	ctx, shutdownCtx := testGroup.Context(), testGroup.ShutdownContext()
+	// NOTE: squad context carries squad.
+	assert.NoError(t, Checkpoint(ctx))
+	assert.NoError(t, ctx.Err())
+	assert.NoError(t, shutdownCtx.Err())
+
+	testGroup.Stop(nil)
+	<-shutdownCtx.Done()
+	// NOTE: squad context keeps running during graceful period.
+	assert.NoError(t, ctx.Err())
+	assert.ErrorIs(t, Checkpoint(ctx), ErrShuttingDown)
+
+	assert.NoError(t, testGroup.Wait())
+	assert.Error(t, ctx.Err())
+}
+
+func TestSquad_State(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+	assert.Equal(t, StateRunning, testGroup.State())
+
+	changes := testGroup.Subscribe()
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, StateStopped, testGroup.State())
+
+	var states []State
+	for change := range changes {
+		states = append(states, change.To)
+	}
+	assert.Equal(t, []State{StateDraining, StateStopping, StateStopped}, states)
+	_, ok := <-testGroup.Subscribe()
+	assert.False(t, ok)
+
+	deferred, err := New(WithDeferredBootstrap())
+	assert.NoError(t, err)
+	assert.Equal(t, "created", deferred.State().String())
+	changes = deferred.Subscribe()
+	deferred.Stop(nil)
+	assert.NoError(t, deferred.Wait())
+	// NOTE: squad without graceful period is not draining.
+	assert.Equal(t, StateChange{From: StateCreated, To: StateStopping}, withoutTime(<-changes))
+
+	delayed, err := New(WithManualTrigger(WithPreShutdownDelay(100*time.Millisecond), WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+	delayed.Stop(nil)
+	// NOTE: squad is draining during pre-shutdown delay.
+	assert.Equal(t, StateDraining, delayed.State())
+	assert.NoError(t, delayed.Wait())
+}
+
+func TestSquad_Hooks(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		edges []string
+	)
+	add := func(edge string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		edges = append(edges, edge)
+	}
+	record := func(edge string) func(context.Context) {
+		return func(ctx context.Context) {
+			// NOTE: hooks get context, which carries squad.
+			_, ok := ShutdownStateFrom(ctx)
+			assert.True(t, ok)
+			add(edge)
+		}
+	}
+
+	testGroup, err := New(
+		WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)),
+		WithHooks(Hooks{OnStarted: record("started"), OnShutdownStart: record("shutdown start")}),
+		WithHooks(Hooks{OnShutdownDone: record("shutdown done")}),
+		WithCloses(func(context.Context) error {
+			add("cleanup")
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, []string{"started", "shutdown start", "cleanup", "shutdown done"}, edges)
+
+	blocked := make(chan time.Duration, 1)
+	testGroup, err = New(
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithHooks(Hooks{OnShutdownStart: func(ctx context.Context) {
+			start := time.Now()
+			<-ctx.Done()
+			blocked <- time.Since(start)
+		}}),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Stop(nil)
+	// NOTE: blocked hook does not delay drain, its context is done at hard deadline.
+	assert.Error(t, testGroup.drainContext().Err())
+	assert.NoError(t, testGroup.Wait())
+	assert.InDelta(t, testGroup.HardDeadline(), <-blocked, float64(50*time.Millisecond))
+
+	// NOTE: squad, New of which has failed, has not started, so it runs no hooks.
+	edges = nil
+	_, err = New(
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithHooks(Hooks{OnStarted: record("started"), OnShutdownStart: record("shutdown start"), OnShutdownDone: record("shutdown done")}),
+		WithBootstrap(failingBootstrap),
+	)
+	assert.Error(t, err)
+	time.Sleep(10 * time.Millisecond)
+	mtx.Lock()
+	assert.Empty(t, edges)
+	mtx.Unlock()
+}
+
+func TestSquad_ForceExit(t *testing.T) {
+	var (
+		output bytes.Buffer
+		exited = make(chan int, 1)
+	)
+	testGroup, err := New(
+		WithManualTrigger(WithGracefulPeriod(100*time.Millisecond), WithShutdownTimeout(50*time.Millisecond)),
+		WithForceExitAfter(50*time.Millisecond),
+		// NOTE: hook is blocked, as if in uncancellable call, until process exits.
+		WithHooks(Hooks{OnShutdownDone: func(context.Context) { <-exited }}),
+	)
+	assert.NoError(t, err)
+	testGroup.forceExit.output = &output
+	testGroup.forceExit.exit = func(code int) { exited <- code }
+
+	start := time.Now()
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	// NOTE: hard deadline is graceful period, which includes shutdown timeout.
+	assert.GreaterOrEqual(t, time.Since(start), testGroup.HardDeadline()+50*time.Millisecond)
+	assert.Contains(t, output.String(), "TestSquad_ForceExit")
+
+	// NOTE: squad stopped in time does not exit.
+	testGroup, err = New(WithManualTrigger(WithShutdownInGracePriod(50*time.Millisecond)), WithForceExitAfter(50*time.Millisecond))
+	assert.NoError(t, err)
+	testGroup.forceExit.exit = func(code int) { exited <- code }
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	select {
+	case <-exited:
+		t.Fatal("squad exited after stop")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// NOTE: pre-shutdown delay is charged to hard deadline, so it does not bring forced exit forward.
+	testGroup, err = New(
+		WithManualTrigger(WithPreShutdownDelay(100*time.Millisecond), WithShutdownInGracePriod(100*time.Millisecond)),
+		WithForceExitAfter(50*time.Millisecond),
+		WithCloses(func(context.Context) error {
+			time.Sleep(80 * time.Millisecond)
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	testGroup.forceExit.exit = func(code int) { exited <- code }
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	select {
+	case <-exited:
+		t.Fatal("squad exited before hard deadline")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// NOTE: failure of New is handled by caller, so it does not exit process.
+	testGroup = &Squad{}
+	_, err = New(
+		WithManualTrigger(WithShutdownInGracePriod(10*time.Millisecond)),
+		WithForceExitAfter(10*time.Millisecond),
+		WithBootstrap(failingBootstrap),
+		func(s *Squad) {
+			testGroup = s
+			s.forceExit.exit = func(code int) { exited <- code }
+		},
+	)
+	assert.Error(t, err)
+	assert.Nil(t, testGroup.forceExit.timer)
+	select {
+	case <-exited:
+		t.Fatal("squad exited after failure of New")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// failingBootstrap fails bootstrap of squad.
+func failingBootstrap(context.Context) error {
+	// NOTE: synx.ErrGroup closes its channel twice, if the last function returns
+	// concurrently with Wait, so bootstrap does not fail instantly.
+	time.Sleep(10 * time.Millisecond)
+	return errors.New("bootstrap")
+}
+
+func withoutTime(change StateChange) StateChange {
+	change.At = time.Time{}
+	return change
+}
+
+func TestSquad_RunHTTP3Server(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(
+		WithGracefulPeriod(time.Second),
+		WithShutdownTimeout(100*time.Millisecond),
+	))
+	assert.NoError(t, err)
+
+	h3 := &testHTTP3Server{testShutdowner: testShutdowner{stop: make(chan struct{})}}
+	tcp := &http.Server{
+		Addr:              "127.0.0.1:0",
+		ReadHeaderTimeout: time.Second,
+		Handler:           http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+	}
+	testGroup.RunHTTP3Server(h3, tcp, ServerOptions{ShutdownTimeout: 50 * time.Millisecond})
+
+	rec := httptest.NewRecorder()
+	tcp.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	assert.NotEmpty(t, rec.Header().Get("Alt-Svc"))
+
+	testGroup.Stop(nil)
+
+	rec = httptest.NewRecorder()
+	tcp.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	assert.Empty(t, rec.Header().Get("Alt-Svc"))
+
+	err = testGroup.Wait()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Eventually(t, h3.closed.Load, time.Second, 10*time.Millisecond)
+}
+
+func TestSquad_LongPoll(t *testing.T) {
+	testGroup, err := New(WithManualTrigger())
+	assert.NoError(t, err)
+
+	events := make(chan int, 1)
+	events <- 1
+	event, err := LongPoll(context.Background(), testGroup, events)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, event)
+
+	time.AfterFunc(50*time.Millisecond, func() { testGroup.Stop(nil) })
+	_, err = LongPoll(context.Background(), testGroup, events)
+	assert.ErrorIs(t, err, ErrShuttingDown)
+}
+
+type testJob struct {
+	processed atomic.Int64
+}
+
+func (j *testJob) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		j.processed.Add(1)
+		<-time.After(time.Millisecond)
+	}
+	return nil
+}
+
+func (j *testJob) Checkpoint(context.Context) ([]byte, error) {
+	return []byte(fmt.Sprint(j.processed.Load())), nil
+}
+
+func TestSquad_Checkpoint(t *testing.T) {
+	checkpoints := make(map[string][]byte)
+	testGroup, err := New(
+		WithManualTrigger(WithGracefulPeriod(200*time.Millisecond), WithShutdownTimeout(100*time.Millisecond)),
+		WithCheckpointHandler(func(_ context.Context, task string, payload []byte) error {
+			checkpoints[task] = payload
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+
+	job := &testJob{}
+	testGroup.Run(job.Run, WithTaskName("batch"), WithCheckpointer(job))
+	testGroup.Run(func(context.Context) error { return nil }, WithTaskName("done"), WithCheckpointer(job))
+
+	time.AfterFunc(50*time.Millisecond, func() { testGroup.Stop(nil) })
+	assert.NoError(t, testGroup.Wait())
+	assert.Len(t, checkpoints, 1)
+	assert.NotEmpty(t, checkpoints["batch"])
+
+	// NOTE: blocked checkpoint is abandoned at hard deadline, which is counted since Stop
+	// including pre-shutdown delay, so checkpoint started after it is not given extra time.
+	testGroup, err = New(
+		WithManualTrigger(
+			WithPreShutdownDelay(100*time.Millisecond),
+			WithGracefulPeriod(200*time.Millisecond),
+			WithShutdownTimeout(100*time.Millisecond),
+		),
+		WithCheckpointHandler(func(ctx context.Context, _ string, _ []byte) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+	assert.NoError(t, err)
+	testGroup.Run(job.Run, WithTaskName("batch"), WithCheckpointer(job))
+
+	start := time.Now()
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, testGroup.Wait(), context.DeadlineExceeded)
+	assert.InDelta(t, testGroup.HardDeadline(), time.Since(start), float64(50*time.Millisecond))
+}
+
+func TestSquad_Errors(t *testing.T) {
+	errCheckpoint := errors.New("checkpoint failed")
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+	assert.Empty(t, testGroup.Errors())
+
+	testGroup.RunAndForget(func(context.Context) error { return errCheckpoint }, testGroup.appendErr)
+	assert.Eventually(t, func() bool {
+		return len(testGroup.Errors()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, testGroup.Errors()[0], errCheckpoint)
+
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, testGroup.Wait(), errCheckpoint)
+}
+
+func TestSquad_PanicPolicy(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	var restarts atomic.Int32
+	testGroup.Run(func(ctx context.Context) error {
+		if restarts.Add(1) < 3 {
+			panic("flaky")
+		}
+		<-ctx.Done()
+		return nil
+	}, WithPanicPolicy(PanicRestart))
+	testGroup.Run(func(context.Context) error {
+		panic("broken")
+	}, WithTaskName("isolated"), WithPanicPolicy(PanicIsolate))
+
+	assert.Eventually(t, func() bool {
+		return restarts.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+	testGroup.Stop(nil)
+
+	err = testGroup.Wait()
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "broken", panicErr.Value)
+	assert.EqualError(t, err, "task isolated failed: panic: broken")
+}
+
+func TestSquad_ConflictingOptions(t *testing.T) {
+	_, err := New(WithManualTrigger(), WithSignalHandler())
+	assert.ErrorIs(t, err, ErrConflictingOptions)
+	assert.EqualError(t, err, "conflicting options: WithManualTrigger and WithSignalHandler")
+
+	_, err = New(WithSignalHandler(WithImmediateCancel(), WithGracePeriodFile("grace", time.Second)))
+	assert.ErrorIs(t, err, ErrConflictingOptions)
+
+	first, err := New(WithCloses(func(context.Context) error { return nil }), WithSignalHandler(WithShutdownTimeout(time.Second)))
+	assert.NoError(t, err)
+	second, err := New(WithSignalHandler(WithShutdownTimeout(time.Second)), WithCloses(func(context.Context) error { return nil }))
+	assert.NoError(t, err)
+	assert.Equal(t, first.ShutdownTimeout(), second.ShutdownTimeout())
+	assert.Equal(t, first.HardDeadline(), second.HardDeadline())
+}
+
+func benchmarkOptions(lowOverhead bool, opts ...Option) []Option {
+	if lowOverhead {
+		return append(opts, WithLowOverhead())
+	}
+	return opts
+}
+
+func BenchmarkSquad_RunWait(b *testing.B) {
+	for _, lowOverhead := range []bool{false, true} {
+		b.Run(fmt.Sprintf("low overhead %v", lowOverhead), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				testGroup, _ := New(benchmarkOptions(lowOverhead)...)
+				testGroup.Run(func(context.Context) error { return nil })
+				_ = testGroup.Wait()
+			}
+		})
+	}
+}
+
+func BenchmarkSquad_Shutdown(b *testing.B) {
+	closes := make([]func(context.Context) error, 100)
+	for i := range closes {
+		closes[i] = func(context.Context) error { return nil }
+	}
+
+	for _, lowOverhead := range []bool{false, true} {
+		b.Run(fmt.Sprintf("low overhead %v", lowOverhead), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				testGroup, _ := New(benchmarkOptions(lowOverhead, WithCloses(closes...))...)
+				_ = testGroup.Wait()
+			}
+		})
+	}
+}
+
+func BenchmarkSquad_Labeled(b *testing.B) {
+	fn := func(context.Context) error { return nil }
+
+	for _, lowOverhead := range []bool{false, true} {
+		b.Run(fmt.Sprintf("low overhead %v", lowOverhead), func(b *testing.B) {
+			s := &Squad{lowOverhead: lowOverhead}
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = s.labeled(ctx, "member", phaseRun, fn)
+			}
+		})
+	}
+}
+
+type shutdownerFunc func(context.Context) error
+
+func (fn shutdownerFunc) Shutdown(ctx context.Context) error {
+	return fn(ctx)
+}
+
+func TestSquad_StandardOrdering(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		order []string
+	)
+	record := func(step string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		order = append(order, step)
+	}
+
+	testGroup, err := New(
+		WithCloses(func(context.Context) error { record("flush"); return nil }),
+		WithSubsystem(nil, func(context.Context) error { record("storage"); return nil }),
+		WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)),
+		WithStandardOrdering(),
+	)
+	assert.NoError(t, err)
+
+	srv := testShutdowner{stop: make(chan struct{})}
+	testGroup.RunShutdowner(srv.Start, shutdownerFunc(func(ctx context.Context) error {
+		record("server")
+		return srv.Shutdown(ctx)
+	}))
+	testGroup.RunConsumer(func(consumeCtx, _ context.Context) error {
+		<-consumeCtx.Done()
+		record("consumer")
+		return nil
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, []string{"server", "consumer", "flush", "storage"}, order)
+}
+
+func TestSquad_Describe(t *testing.T) {
+	testGroup, err := New()
+	assert.NoError(t, err)
+
+	var attempts atomic.Int32
+	testGroup.Run(func(ctx context.Context) error {
+		if attempts.Add(1) < 3 {
+			panic("crash")
+		}
+		<-ctx.Done()
+		return nil
+	}, WithTaskName("worker"), WithPanicPolicy(PanicRestart))
+
+	assert.Eventually(t, func() bool {
+		return attempts.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+
+	statuses := testGroup.Describe()
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "worker", statuses[0].Name)
+	assert.True(t, statuses[0].Running)
+	assert.Equal(t, 2, statuses[0].Restarts)
+	assert.EqualError(t, statuses[0].LastError, "panic: crash")
+	assert.False(t, statuses[0].LastErrorAt.IsZero())
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.False(t, testGroup.Describe()[0].Running)
+}
+
+func TestSquad_StopReason(t *testing.T) {
+	errLicense := errors.New("licence expired")
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	causes := make(chan error, 1)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	})
+
+	testGroup.Stop(errLicense)
+	assert.ErrorIs(t, testGroup.Wait(), errLicense)
+	assert.ErrorIs(t, <-causes, errLicense)
+}
+
+func TestSquad_CleanupDeadline(t *testing.T) {
+	var remaining time.Duration
+	testGroup, err := New(
+		WithManualTrigger(WithShutdownInGracePriod(300*time.Millisecond)),
+		WithCloses(func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			remaining = time.Until(deadline)
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(150 * time.Millisecond)
+		return nil
+	}, WithStopTimeout(time.Second))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Greater(t, remaining, time.Duration(0))
+	assert.Less(t, remaining, 200*time.Millisecond)
+}
+
+func TestSquad_ShutdownPhases(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		order []string
+	)
+	record := func(step string) func(context.Context) error {
+		return func(context.Context) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			order = append(order, step)
+			return nil
+		}
+	}
+
+	testGroup, err := New(
+		WithManualTrigger(WithShutdownInGracePriod(time.Second)),
+		WithStandardOrdering(),
+		WithShutdownPhase("deregister", 50*time.Millisecond, record("deregister"), func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+		WithShutdownPhase("storage", time.Second, record("storage")),
+		WithSubsystem(nil, record("subsystem")),
+		WithCloses(record("cleanup")),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Run(func(context.Context) error { return nil })
+	err = testGroup.Wait()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualError(t, err, "shutdown phase deregister: context deadline exceeded")
+	assert.Equal(t, []string{"cleanup", "deregister", "storage", "subsystem"}, order)
+	assert.Len(t, testGroup.Report().Cleanups, 5)
+
+	// NOTE: without standard ordering phases still run before subsystems are closed.
+	order = nil
+	testGroup, err = New(
+		WithManualTrigger(WithShutdownInGracePriod(time.Second)),
+		WithShutdownPhase("deregister", time.Second, record("deregister")),
+		WithSubsystem(nil, record("subsystem")),
+		WithCloses(record("cleanup")),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Run(func(context.Context) error { return nil })
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, []string{"cleanup", "deregister", "subsystem"}, order)
+
+	testGroup, err = New(
+		WithManualTrigger(WithGracefulPeriod(200*time.Millisecond), WithShutdownTimeout(100*time.Millisecond)),
+		WithShutdownPhase("deregister", time.Minute, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Run(func(context.Context) error { return nil })
+	start := time.Now()
+	assert.ErrorIs(t, testGroup.Wait(), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSquad_RunRefresher(t *testing.T) {
+	errRefresh := errors.New("refresh failed")
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	var refreshes atomic.Int32
+	testGroup.RunRefresher(10*time.Millisecond, func(context.Context) error {
+		if refreshes.Add(1) > 1 {
+			return errRefresh
+		}
+		return nil
+	}, WithTaskName("cache"))
+
+	assert.Eventually(t, func() bool {
+		return errors.Is(testGroup.Ready(context.Background()), errRefresh)
+	}, time.Second, 5*time.Millisecond)
+	assert.EqualError(t, testGroup.Ready(context.Background()), "subsystem cache: refresh failed")
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	stopped := refreshes.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, stopped, refreshes.Load())
+}
+
+func TestSquad_PanicHandler(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		values []any
+	)
+	testGroup, err := New(
+		WithPanicHandler(func(value any, stack []byte) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			values = append(values, value)
+			assert.NotEmpty(t, stack)
+		}),
+		WithCloses(func(context.Context) error { panic("cleanup") }),
+	)
+	assert.NoError(t, err)
+
+	testGroup.RunGracefully(func(context.Context) error { panic("member") }, nil)
+	detached := make(chan error, 1)
+	testGroup.RunAndForget(func(context.Context) error { panic("detached") }, func(err error) { detached <- err })
+
+	err = testGroup.Wait()
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.ElementsMatch(t, []any{"member", "detached", "cleanup"}, values)
+	assert.ErrorAs(t, <-detached, &panicErr)
+	assert.Equal(t, "detached", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+
+	// NOTE: panic of detached function is handled by default panic policy.
+	testGroup, err = New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	testGroup.RunAndForget(func(context.Context) error { panic("detached") }, nil)
+	assert.ErrorAs(t, testGroup.Wait(), &panicErr)
+
+	var attempts atomic.Int32
+	testGroup, err = New(WithDefaultPanicPolicy(PanicRestart))
+	assert.NoError(t, err)
+	testGroup.RunAndForget(func(context.Context) error {
+		if attempts.Add(1) == 1 {
+			panic("detached")
+		}
+		return nil
+	}, func(err error) { t.Errorf("restarted detached function failed: %v", err) })
+	assert.Eventually(t, func() bool { return attempts.Load() == 2 }, time.Second, 10*time.Millisecond)
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_ShutdownState(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+
+	states := make(chan ShutdownState, 2)
+	handler := testGroup.InjectShutdownState(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		state, ok := ShutdownStateFrom(r.Context())
+		assert.True(t, ok)
+		states <- state
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	assert.Equal(t, ShutdownState{}, <-states)
+
+	testGroup.Stop(nil)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	state := <-states
+	assert.True(t, state.Draining)
+	assert.Greater(t, state.Remaining, time.Duration(0))
+	assert.LessOrEqual(t, state.Remaining, 500*time.Millisecond)
+
+	_, ok := ShutdownStateFrom(context.Background())
+	assert.False(t, ok)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_RunSupervised(t *testing.T) {
+	errTransient := errors.New("transient")
+	errFatal := errors.New("fatal")
+
+	testGroup, err := New()
+	assert.NoError(t, err)
+
+	var attempts atomic.Int32
+	testGroup.RunSupervised(func(context.Context) error {
+		if attempts.Add(1) < 3 {
+			return errTransient
+		}
+		return errFatal
+	}, RestartPolicy{MaxRestarts: 5, Backoff: Backoff{Initial: time.Millisecond}, RestartOn: func(err error) bool {
+		return errors.Is(err, errTransient)
+	}}, WithTaskName("worker"))
+
+	err = testGroup.Wait()
+	assert.ErrorIs(t, err, errFatal)
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, 2, testGroup.Describe()[0].Restarts)
+
+	testGroup, err = New()
+	assert.NoError(t, err)
+	testGroup.RunSupervised(func(context.Context) error {
+		return errTransient
+	}, RestartPolicy{MaxRestarts: 2})
+	assert.ErrorIs(t, testGroup.Wait(), errTransient)
+	assert.Equal(t, 2, testGroup.Describe()[0].Restarts)
+}
+
+func TestSquad_RestartLog(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	out := &syncBuffer{}
+	testGroup, err := New(
+		WithLogger(slog.New(slog.NewTextHandler(out, nil))),
+		WithHealthEndpoint(addr),
+		WithManualTrigger(WithShutdownInGracePriod(time.Second)),
+		WithRestartLogInterval(50*time.Millisecond),
+	)
+	assert.NoError(t, err)
+
+	errUnavailable := errors.New("unavailable")
+	var attempts atomic.Int32
+	testGroup.RunSupervised(func(ctx context.Context) error {
+		if attempts.Add(1) <= 5 {
+			return errUnavailable
+		}
+		<-ctx.Done()
+		return nil
+	}, RestartPolicy{MaxRestarts: -1}, WithTaskName("worker"))
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), `msg="member flapping" name=worker restarts=4 total_restarts=5 flaps=1`)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, strings.Count(out.String(), `msg="member restarting" name=worker restarts=1 error=unavailable`))
+	assert.Equal(t, 1, strings.Count(out.String(), "member restarting"))
+
+	resp, err := http.Get("http://" + addr + "/members")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, "worker: running=true restarts=5 flaps=1 goroutines=1\n", string(body))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, 1, testGroup.Describe()[0].Flaps)
+}
+
+func TestSquad_Checkpoint_CPUBound(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	yielded := make(chan error, 1)
+	testGroup.Run(func(ctx context.Context) error {
+		for {
+			if err := Checkpoint(ctx); err != nil {
+				yielded <- err
+				return nil
+			}
+		}
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, <-yielded, ErrShuttingDown)
+	assert.NoError(t, testGroup.Wait())
+	assert.NoError(t, Checkpoint(context.Background()))
+}
+
+type testGRPCServer struct {
+	stop    chan struct{}
+	stopped atomic.Bool
+}
+
+func (s *testGRPCServer) Serve(net.Listener) error {
+	<-s.stop
+	return nil
+}
+
+func (s *testGRPCServer) GracefulStop() {
+	// NOTE: streams are not drained until server is stopped.
+	<-s.stop
+}
+
+func (s *testGRPCServer) Stop() {
+	s.stopped.Store(true)
+	close(s.stop)
+}
+
+func TestSquad_RunGRPCServer(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(100*time.Millisecond)))
+	assert.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	srv := &testGRPCServer{stop: make(chan struct{})}
+	testGroup.RunGRPCServer(srv, ln, ServerOptions{ShutdownTimeout: 50 * time.Millisecond})
+
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, testGroup.Wait(), context.DeadlineExceeded)
+	assert.True(t, srv.stopped.Load())
+}
+
+func TestSquad_HealthEndpoint(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	probe := func(path string) int {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return 0
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	var live, ready int
+	testGroup, err := New(
+		WithHealthEndpoint(addr),
+		WithManualTrigger(WithShutdownInGracePriod(time.Second)),
+		WithCloses(func(context.Context) error {
+			live, ready = probe("/live"), probe("/ready")
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, probe("/ready"))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, http.StatusOK, live)
+	assert.Equal(t, http.StatusServiceUnavailable, ready)
+	assert.Equal(t, 0, probe("/live"))
+
+	_, err = New(WithHealthEndpoint("invalid address"))
+	assert.Error(t, err)
+
+	// NOTE: failed New does not keep health endpoint bound.
+	_, err = New(
+		WithHealthEndpoint(addr),
+		WithBootstrap(failingBootstrap),
+	)
+	assert.Error(t, err)
+	ln, err = net.Listen("tcp", addr)
+	assert.NoError(t, err)
+	assert.NoError(t, ln.Close())
+}
+
+func TestSquad_BootstrapFailurePolicy(t *testing.T) {
+	errInit := errors.New("database is unavailable")
+
+	var attempts atomic.Int32
+	testGroup, err := New(
+		WithBootstrapFailurePolicy(BootstrapRetry),
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithBootstrap(func(context.Context) error {
+			if attempts.Add(1) < 3 {
+				return errInit
+			}
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, testGroup.Ready(context.Background()), ErrNotBootstrapped)
+
+	started := make(chan struct{})
+	testGroup.Run(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	<-started
+	assert.Eventually(t, func() bool {
+		return testGroup.Ready(context.Background()) == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(3), attempts.Load())
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+
+	testGroup, err = New(
+		WithBootstrapFailurePolicy(BootstrapDegraded),
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithBootstrap(func(context.Context) error { return errInit }),
+	)
+	assert.NoError(t, err)
+	testGroup.Run(func(context.Context) error {
+		t.Error("member of degraded squad must not be launched")
+		return nil
+	})
+	assert.EqualError(t, testGroup.Ready(context.Background()), "bootstrap failed: database is unavailable")
+
+	time.AfterFunc(50*time.Millisecond, func() { testGroup.Stop(nil) })
+	assert.ErrorIs(t, testGroup.Wait(), errInit)
+}
+
+func TestSquad_RunUntilComplete(t *testing.T) {
+	testGroup, err := New(WithRunUntilComplete(), WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+
+	srv := testShutdowner{stop: make(chan struct{})}
+	testGroup.RunShutdowner(srv.Start, srv)
+
+	var migrated atomic.Bool
+	testGroup.Run(func(context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		migrated.Store(true)
+		return nil
+	})
+
+	err = testGroup.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ExitCode(err))
+	assert.True(t, migrated.Load())
+	<-srv.stop
+
+	assert.Equal(t, 1, ExitCode(errors.New("migration failed")))
+}
+
+type syncBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.String()
+}
+
+func TestSquad_Logger(t *testing.T) {
+	out := &syncBuffer{}
+	testGroup, err := New(
+		WithLogger(slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithSubsystem(nil, func(context.Context) error { return nil }, WithSubsystemName("postgres")),
+	)
+	assert.NoError(t, err)
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+
+	logs := out.String()
+	for _, event := range []string{
+		`msg="squad started"`,
+		`msg="graceful period started"`,
+		`msg="cleanup started" name=postgres`,
+		`msg="cleanup finished" name=postgres`,
+		`msg="squad stopped"`,
+	} {
+		assert.Contains(t, logs, event)
+	}
+}
+
+func TestSquad_MaxConnectionAge(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	var (
+		mtx   sync.Mutex
+		conns = make(map[string]struct{})
+	)
+	testGroup.RunServerWithOptions(&http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: time.Second,
+		Handler: http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(100 * time.Millisecond)
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			conns[r.RemoteAddr] = struct{}{}
+		}),
+	}, ServerOptions{MaxConnectionAge: 50 * time.Millisecond})
+
+	client := &http.Client{Transport: &http.Transport{}}
+	request := func(path string) int {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			return 0
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(conns)
+	}
+
+	assert.Eventually(t, func() bool { return request("/") == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, request("/"))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 2, request("/"))
+
+	// NOTE: connection expired while active is closed, when it becomes idle.
+	assert.Equal(t, 2, request("/slow"))
+	assert.Eventually(t, func() bool { return request("/") == 3 }, time.Second, 10*time.Millisecond)
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_SyncPrimitives(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+
+	strict := testGroup.Semaphore(1, time.Second)
+	lenient := testGroup.Semaphore(1, 0)
+	assert.NoError(t, strict.Acquire(context.Background()))
+	strict.Release()
+
+	wg := testGroup.WaitGroup()
+	var ran atomic.Int32
+	assert.NoError(t, wg.Go(func(context.Context) { ran.Add(1) }))
+	assert.NoError(t, wg.Wait(context.Background()))
+
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, strict.Acquire(context.Background()), ErrShuttingDown)
+	assert.NoError(t, lenient.Acquire(context.Background()))
+	lenient.Release()
+	assert.ErrorIs(t, wg.Go(func(context.Context) { ran.Add(1) }), ErrShuttingDown)
+	assert.Equal(t, int32(1), ran.Load())
+
+	assert.NoError(t, testGroup.Wait())
+	assert.ErrorIs(t, lenient.Acquire(context.Background()), ErrShuttingDown)
+}
+
+func TestSquad_ShutdownReason(t *testing.T) {
+	errTask := errors.New("failed task")
+
+	testGroup, err := New()
+	assert.NoError(t, err)
+	assert.Equal(t, ReasonNone, testGroup.ShutdownReason().Kind)
+	testGroup.Run(func(context.Context) error { return errTask }, WithTaskName("worker"))
+	assert.Error(t, testGroup.Wait())
+	reason := testGroup.ShutdownReason()
+	assert.Equal(t, ReasonTaskFailed, reason.Kind)
+	assert.EqualError(t, reason.Err, "task worker failed: failed task")
+
+	testGroup, err = New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, ShutdownReason{Kind: ReasonStopped}, testGroup.ShutdownReason())
+
+	testGroup, err = New()
+	assert.NoError(t, err)
+	testGroup.Run(func(context.Context) error { return nil })
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, ShutdownReason{Kind: ReasonCompleted}, testGroup.ShutdownReason())
+}
+
+func TestSquad_ShutdownBudget(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(time.Second)))
+	assert.NoError(t, err)
+
+	cache, err := testGroup.ShutdownBudget("cache", 0.25)
+	assert.NoError(t, err)
+	_, err = testGroup.ShutdownBudget("queue", 0.8)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+
+	remaining := make(chan time.Duration, 1)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		budgetCtx, cancel := cache.Context(context.Background())
+		defer cancel()
+		deadline, _ := budgetCtx.Deadline()
+		remaining <- time.Until(deadline)
+		return nil
+	})
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.InDelta(t, 250*time.Millisecond, <-remaining, float64(50*time.Millisecond))
+}
+
+func TestSquad_Listen(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	ln, err := testGroup.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	testGroup.Run(func(context.Context) error { return errors.New("failed start") })
+	assert.Error(t, testGroup.Wait())
+
+	// NOTE: port is released, although squad has not been drained.
+	rebound, err := net.Listen("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	assert.NoError(t, rebound.Close())
+}
+
+func TestSquad_CleanupTimeout(t *testing.T) {
+	var metrics, database, flush atomic.Int64
+	remaining := func(ctx context.Context) int64 {
+		deadline, _ := ctx.Deadline()
+		return int64(time.Until(deadline))
+	}
+
+	testGroup, err := New(
+		WithManualTrigger(WithShutdownInGracePriod(time.Second)),
+		WithClose(50*time.Millisecond, func(ctx context.Context) error {
+			metrics.Store(remaining(ctx))
+			return nil
+		}),
+		WithCloses(func(ctx context.Context) error {
+			database.Store(remaining(ctx))
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	testGroup.RunGracefully(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, func(ctx context.Context) error {
+		flush.Store(remaining(ctx))
+		return nil
+	}, WithCleanupTimeout(100*time.Millisecond))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.LessOrEqual(t, time.Duration(metrics.Load()), 50*time.Millisecond)
+	assert.LessOrEqual(t, time.Duration(flush.Load()), 100*time.Millisecond)
+	assert.Greater(t, time.Duration(flush.Load()), 50*time.Millisecond)
+	assert.Greater(t, time.Duration(database.Load()), 500*time.Millisecond)
+}
+
+func TestSquad_SubsystemDeps(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		events []string
+	)
+	record := func(event string) func(context.Context) error {
+		return func(context.Context) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			events = append(events, event)
+			return nil
+		}
+	}
+
+	testGroup, err := New(
+		WithSubsystemDeps("http", []string{"db", "cache"}, record("init http"), record("close http")),
+		WithSubsystemDeps("cache", []string{"db"}, record("init cache"), record("close cache")),
+		WithSubsystemDeps("db", nil, record("init db"), record("close db")),
+	)
+	assert.NoError(t, err)
+	testGroup.Run(func(context.Context) error { return nil })
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, []string{
+		"init db", "init cache", "init http",
+		"close http", "close cache", "close db",
+	}, events)
+	assert.Len(t, testGroup.Report().Cleanups, 3)
+
+	_, err = New(WithSubsystemDeps("http", []string{"db"}, nil, nil))
+	assert.ErrorIs(t, err, ErrInvalidDependencies)
+	_, err = New(
+		WithSubsystemDeps("a", []string{"b"}, nil, nil),
+		WithSubsystemDeps("b", []string{"a"}, nil, nil),
+	)
+	assert.ErrorIs(t, err, ErrInvalidDependencies)
+
+	errInit := errors.New("failed init")
+	events = nil
+	testGroup, err = New(
+		WithDeferredBootstrap(),
+		WithSubsystemDeps("db", nil, record("init db"), record("close db")),
+		WithSubsystemDeps("cache", []string{"db"}, func(context.Context) error { return errInit }, record("close cache")),
+	)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, testGroup.Wait(), errInit)
+	assert.Equal(t, []string{"init db", "close db"}, events)
+}
+
+func TestSquad_AtExit(t *testing.T) {
+	var closed atomic.Bool
+	testGroup, err := New(WithSubsystem(nil, func(context.Context) error {
+		closed.Store(true)
+		return nil
+	}, WithSubsystemName("db")))
+	assert.NoError(t, err)
+
+	var reports []Report
+	testGroup.AtExit(func(report Report) {
+		assert.True(t, closed.Load())
+		reports = append(reports, report)
+	})
+	testGroup.AtExit(func(Report) { panic("marker") })
+	testGroup.Run(func(context.Context) error { return nil })
+
+	var panicErr *PanicError
+	err = testGroup.Wait()
+	assert.ErrorAs(t, err, &panicErr)
+	// NOTE: subsequent Wait returns result of first one without running exit hooks again.
+	assert.Equal(t, err, testGroup.Wait())
+	assert.Len(t, reports, 1)
+	assert.Equal(t, "db", reports[0].Cleanups[0].Name)
+	assert.True(t, reports[0].Cleanups[0].Ran)
+}
+
+func TestSquad_Warmup(t *testing.T) {
+	testGroup, err := New(WithWarmup(100*time.Millisecond, nil))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, testGroup.Ready(context.Background()), ErrWarmingUp)
+	assert.Eventually(t, func() bool {
+		return testGroup.Ready(context.Background()) == nil
+	}, time.Second, 10*time.Millisecond)
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+
+	var warm atomic.Bool
+	testGroup, err = New(WithWarmup(time.Hour, func(context.Context) error {
+		if !warm.Load() {
+			return errors.New("cold cache")
+		}
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.ErrorContains(t, testGroup.Ready(context.Background()), "cold cache")
+	warm.Store(true)
+	assert.NoError(t, testGroup.Ready(context.Background()))
+	warm.Store(false)
+	assert.NoError(t, testGroup.Ready(context.Background()))
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_WithContext(t *testing.T) {
+	type traceKey struct{}
+	parent, cancel := context.WithCancelCause(context.WithValue(context.Background(), traceKey{}, "trace"))
+
+	testGroup, err := New(WithContext(parent), WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)))
+	assert.NoError(t, err)
+	trace := make(chan any, 1)
+	testGroup.Run(func(ctx context.Context) error {
+		trace <- ctx.Value(traceKey{})
+		<-ctx.Done()
+		return nil
+	})
+
+	errOuter := errors.New("outer shutdown")
+	cancel(errOuter)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, "trace", <-trace)
+	assert.Equal(t, ShutdownReason{Kind: ReasonStopped, Err: errOuter}, testGroup.ShutdownReason())
+}
+
+func TestSquad_ListenDualStack(t *testing.T) {
+	probe, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback is not available: %v", err)
+	}
+	assert.NoError(t, probe.Close())
+
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(100 * time.Millisecond)))
+	assert.NoError(t, err)
+
+	ln, err := testGroup.ListenDualStack(":0")
+	assert.NoError(t, err)
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})}
+	testGroup.RunShutdowner(func(context.Context) error { return srv.Serve(ln) }, srv)
+
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		resp, err := http.Get("http://" + net.JoinHostPort(host, port))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, "ok", string(body))
+	}
+
+	busy, err := net.Listen("tcp6", net.JoinHostPort("::", "0"))
+	assert.NoError(t, err)
+	defer busy.Close()
+	_, err = testGroup.ListenDualStack(busy.Addr().String()[len("[::]"):])
+	assert.ErrorContains(t, err, "listen tcp6")
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	_, err = net.Dial("tcp4", net.JoinHostPort("127.0.0.1", port))
+	assert.Error(t, err)
+
+}
+
+func TestSquad_ListenDualStack_TemporaryError(t *testing.T) {
+	// NOTE: temporary error of one family is retried by server, it does not stop both.
+	flaky := newMultiListener(temporaryListener{})
+	defer flaky.Close()
+	_, err := flaky.Accept()
+	// NOTE: http.Server.Serve asserts type of error instead of unwrapping it.
+	netErr, ok := err.(net.Error) //nolint:errorlint // as checked by http.Server.Serve.
+	assert.True(t, ok)
+	assert.True(t, ok && netErr.Temporary()) //nolint:staticcheck // as checked by http.Server.Serve.
+}
+
+// temporaryListener always fails to accept with temporary error, e.g. EMFILE.
+type temporaryListener struct{ net.Listener }
+
+func (temporaryListener) Accept() (net.Conn, error) { return nil, temporaryError{} }
+
+func (temporaryListener) Close() error { return nil }
+
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "too many open files" }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
+func TestSquad_PreShutdownDelay(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(
+		WithPreShutdownDelay(200*time.Millisecond),
+		WithShutdownInGracePriod(100*time.Millisecond),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, 300*time.Millisecond, testGroup.HardDeadline())
+
+	var remaining atomic.Int64
+	testGroup.Run(func(ctx context.Context) error {
+		<-testGroup.Draining()
+		remaining.Store(int64(testGroup.DrainTimer().Extend(time.Minute)))
+		testGroup.DrainTimer().Finish()
+		<-ctx.Done()
+		return nil
+	})
+
+	start := time.Now()
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, testGroup.Ready(context.Background()), ErrShuttingDown)
+	assert.NoError(t, testGroup.drainContext().Err())
+	assert.NoError(t, testGroup.Wait())
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	// NOTE: hard deadline is counted since beginning of shutdown including pre-shutdown delay.
+	assert.LessOrEqual(t, time.Duration(remaining.Load()), 100*time.Millisecond)
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestSquad_DrainTimer(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(800*time.Millisecond)))
+	assert.NoError(t, err)
+	assert.Zero(t, testGroup.DrainTimer().Extend(time.Second))
+
+	var canceledAfter atomic.Int64
+	testGroup.Run(func(ctx context.Context) error {
+		<-testGroup.Draining()
+		start := time.Now()
+		timer, ok := DrainTimerFrom(ctx)
+		assert.True(t, ok)
+		assert.InDelta(t, 700*time.Millisecond, timer.Extend(500*time.Millisecond), float64(50*time.Millisecond))
+		<-ctx.Done()
+		canceledAfter.Store(int64(time.Since(start)))
+		return nil
+	}, WithStopTimeout(time.Second))
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.GreaterOrEqual(t, time.Duration(canceledAfter.Load()), 650*time.Millisecond)
+
+	testGroup, err = New(WithManualTrigger(WithGracefulPeriod(10 * time.Second)))
+	assert.NoError(t, err)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	start := time.Now()
+	testGroup.Stop(nil)
+	assert.LessOrEqual(t, testGroup.DrainTimer().Extend(time.Minute), testGroup.HardDeadline())
+	testGroup.DrainTimer().Finish()
+	assert.NoError(t, testGroup.Wait())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSquad_RunPeriodic(t *testing.T) {
+	testGroup, err := New(WithManualTrigger(WithShutdownInGracePriod(time.Second)))
+	assert.NoError(t, err)
+
+	var runs atomic.Int32
+	inflight := make(chan struct{})
+	testGroup.RunPeriodic(10*time.Millisecond, func(context.Context) error {
+		if runs.Add(1) == 3 {
+			close(inflight)
+			time.Sleep(100 * time.Millisecond)
+		}
+		return nil
+	})
+
+	<-inflight
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, int32(3), runs.Load())
+
+	errSweep := errors.New("failed sweep")
+	testGroup, err = New()
+	assert.NoError(t, err)
+	testGroup.RunPeriodic(10*time.Millisecond, func(context.Context) error { return errSweep })
+	assert.ErrorIs(t, testGroup.Wait(), errSweep)
+}
+
+func TestSquad_FailureDrainsServers(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		events []string
+	)
+	record := func(event string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		events = append(events, event)
+	}
+
+	testGroup, err := New(
+		WithManualTrigger(WithGracefulPeriod(10*time.Second), WithShutdownTimeout(time.Second)),
+		WithCloses(func(context.Context) error {
+			record("cleanup")
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	entered := make(chan struct{})
+	srv := &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		time.Sleep(100 * time.Millisecond)
+		record("request")
+	})}
+	srv.RegisterOnShutdown(func() { record("shutdown") })
+	testGroup.RunServer(srv)
+
+	errTask := errors.New("failed task")
+	testGroup.Run(func(context.Context) error {
+		<-entered
+		return errTask
+	})
+
+	var resp *http.Response
+	assert.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + addr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	assert.ErrorIs(t, testGroup.Wait(), errTask)
+	assert.Equal(t, []string{"shutdown", "request", "cleanup"}, events)
+}
+
+func TestSquad_WithoutSignalHandler(t *testing.T) {
+	freeAddr := func(t *testing.T) string {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer ln.Close()
+		return ln.Addr().String()
+	}
+	waitCtx := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+
+	testcases := map[string]func(t *testing.T, s *Squad){
+		"Run":           func(_ *testing.T, s *Squad) { s.Run(waitCtx) },
+		"RunGracefully": func(_ *testing.T, s *Squad) { s.RunGracefully(waitCtx, func(context.Context) error { return nil }) },
+		"RunAndForget":  func(_ *testing.T, s *Squad) { s.RunAndForget(waitCtx, nil) },
+		"RunSupervised": func(_ *testing.T, s *Squad) { s.RunSupervised(waitCtx, RestartPolicy{}) },
+		"RunPeriodic":   func(_ *testing.T, s *Squad) { s.RunPeriodic(time.Millisecond, waitCtx) },
+		"RunRefresher":  func(_ *testing.T, s *Squad) { s.RunRefresher(time.Millisecond, waitCtx) },
+		"RunConsumer": func(_ *testing.T, s *Squad) {
+			s.RunConsumer(func(consumeCtx, _ context.Context) error { return waitCtx(consumeCtx) })
+		},
+		"RunServer": func(t *testing.T, s *Squad) {
+			s.RunServer(&http.Server{Addr: freeAddr(t)})
+		},
+		"RunShutdowner": func(_ *testing.T, s *Squad) {
+			srv := testShutdowner{stop: make(chan struct{})}
+			s.RunShutdowner(srv.Start, srv)
+		},
+		"RunGRPCServer": func(t *testing.T, s *Squad) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(t, err)
+			s.RunGRPCServer(&testGRPCServer{stop: make(chan struct{})}, ln, ServerOptions{ShutdownTimeout: 50 * time.Millisecond})
+		},
+		"RunHTTP3Server": func(t *testing.T, s *Squad) {
+			h3 := &testHTTP3Server{testShutdowner: testShutdowner{stop: make(chan struct{})}}
+			s.RunHTTP3Server(h3, &http.Server{Addr: freeAddr(t)}, ServerOptions{ShutdownTimeout: 50 * time.Millisecond})
+		},
+	}
+
+	for name, run := range testcases {
+		name, run := name, run
+		t.Run(name, func(t *testing.T) {
+			testGroup, err := New(WithCloses(func(ctx context.Context) error {
+				_, ok := ctx.Deadline()
+				assert.True(t, ok)
+				return nil
+			}))
+			assert.NoError(t, err)
+			assert.Zero(t, testGroup.GracefulPeriod())
+			run(t, testGroup)
+
+			start := time.Now()
+			testGroup.Stop(nil)
+			err = testGroup.Wait()
+			// NOTE: test gRPC and HTTP/3 servers are closed forcibly.
+			if name == "RunGRPCServer" || name == "RunHTTP3Server" {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+
+	_, err := New(WithStandardOrdering())
+	assert.ErrorIs(t, err, ErrShutdownHandlerRequired)
+}
+
+func TestSquad_RunWithRetry(t *testing.T) {
+	errTemporary := errors.New("temporary failure")
+
+	testGroup, err := New()
+	assert.NoError(t, err)
+	var attempts atomic.Int32
+	testGroup.RunWithRetry(func(context.Context) error {
+		if attempts.Add(1) < 3 {
+			return errTemporary
+		}
+		return nil
+	}, Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond, Jitter: 0.5}, 3)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, int32(3), attempts.Load())
+
+	testGroup, err = New()
+	assert.NoError(t, err)
+	attempts.Store(0)
+	testGroup.RunWithRetry(func(context.Context) error {
+		attempts.Add(1)
+		return errTemporary
+	}, Backoff{Initial: time.Millisecond}, 2)
+	assert.ErrorIs(t, testGroup.Wait(), errTemporary)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestSquad_EarlyBinding(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	initialized := make(chan struct{})
+	testGroup, err := New(
+		WithDeferredBootstrap(),
+		WithEarlyBinding(),
+		WithManualTrigger(WithShutdownInGracePriod(100*time.Millisecond)),
+		WithBootstrap(func(context.Context) error {
+			// NOTE: port is bound, but it is not served until bootstrap has been completed.
+			_, err := net.Listen("tcp", addr)
+			assert.Error(t, err)
+			close(initialized)
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	testGroup.RunServer(&http.Server{Addr: addr, Handler: http.NotFoundHandler()})
+	go func() {
+		<-initialized
+		assert.Eventually(t, func() bool {
+			resp, err := http.Get("http://" + addr)
+			if err != nil {
+				return false
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode == http.StatusNotFound
+		}, time.Second, 10*time.Millisecond)
+		testGroup.Stop(nil)
+	}()
+	assert.NoError(t, testGroup.Wait())
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer busy.Close()
+
+	var bootstrapped atomic.Bool
+	testGroup, err = New(
+		WithDeferredBootstrap(),
+		WithEarlyBinding(),
+		WithBootstrap(func(context.Context) error {
+			bootstrapped.Store(true)
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	testGroup.RunServer(&http.Server{Addr: busy.Addr().String()})
+	assert.Error(t, testGroup.Ready(context.Background()))
+	assert.ErrorContains(t, testGroup.Wait(), "address already in use")
+	assert.False(t, bootstrapped.Load())
+}
+
+func TestSquad_RunPool(t *testing.T) {
+	testGroup, err := New()
+	assert.NoError(t, err)
+
+	var workers atomic.Int32
+	pool := testGroup.RunPool(3, func(ctx context.Context) error {
+		workers.Add(1)
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTaskName("pool"))
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, WithTaskName("other"))
+	assert.Eventually(t, func() bool { return workers.Load() == 3 }, time.Second, time.Millisecond)
+
+	pool.Stop()
+	assert.Eventually(t, func() bool { return !testGroup.Describe()[0].Running }, time.Second, time.Millisecond)
+	assert.True(t, testGroup.Describe()[1].Running)
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+
+	errWorker := errors.New("failed worker")
+	testGroup, err = New()
+	assert.NoError(t, err)
+	var started atomic.Int32
+	testGroup.RunPool(2, func(ctx context.Context) error {
+		if started.Add(1) == 1 {
+			return errWorker
+		}
+		<-ctx.Done()
+		return nil
+	})
+	err = testGroup.Wait()
+	assert.ErrorIs(t, err, errWorker)
+	assert.Regexp(t, `worker \d: failed worker`, err.Error())
+
+	// NOTE: panic of worker does not crash process, it is handled by panic policy of pool.
+	testGroup, err = New()
+	assert.NoError(t, err)
+	testGroup.RunPool(2, func(ctx context.Context) error {
+		if member, _ := pprof.Label(ctx, memberLabel); member != "pool" {
+			return errors.New("unlabeled worker")
+		}
+		panic("boom")
+	}, WithTaskName("pool"), WithPanicPolicy(PanicIsolate))
+	err = testGroup.Wait()
+	var panicErr *PanicError
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Regexp(t, `worker \d: panic: boom`, err.Error())
+}
+
+func TestSquad_Goroutines(t *testing.T) {
+	testGroup, err := New()
+	assert.NoError(t, err)
+
+	started := make(chan struct{}, 2)
+	testGroup.Run(func(ctx context.Context) error {
+		for i := 0; i < 2; i++ {
+			go func() {
+				started <- struct{}{}
+				<-ctx.Done()
+			}()
+		}
+		<-ctx.Done()
+		return nil
+	}, WithTaskName("worker"))
+	<-started
+	<-started
+
+	assert.Equal(t, 3, testGroup.Goroutines()["worker"])
+	assert.Equal(t, 3, testGroup.Describe()[0].Goroutines)
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func TestSquad_DrainStrategy(t *testing.T) {
+	var requeued, handedOff []int
+	testCases := map[string]struct {
+		strategy        DrainStrategy
+		expectedHandled []int
+		expectedPending *[]int
+	}{
+		"finish": {
+			expectedHandled: []int{1, 2, 3},
+		},
+		"requeue": {
+			strategy: Requeue(func(_ context.Context, item int) error {
+				requeued = append(requeued, item)
+				return nil
+			}),
+			expectedHandled: []int{1},
+			expectedPending: &requeued,
+		},
+		"hand off": {
+			strategy: HandOff(func(_ context.Context, items []int) error {
+				handedOff = append(handedOff, items...)
+				return nil
+			}),
+			expectedHandled: []int{1},
+			expectedPending: &handedOff,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+			assert.NoError(t, err)
+
+			fetched := make(chan struct{})
+			handled := make(chan []int, 1)
+			testGroup.RunConsumer(func(consumeCtx, handleCtx context.Context) error {
+				var done []int
+				defer func() { handled <- done }()
+
+				batch := []int{1, 2, 3}
+				close(fetched)
+				for i, item := range batch {
+					if taken, err := Unhandled(handleCtx, batch[i:]); taken {
+						return err
+					}
+					<-consumeCtx.Done()
+					done = append(done, item)
+				}
+				return nil
+			}, WithDrainStrategy(tc.strategy))
+
+			<-fetched
+			testGroup.Stop(nil)
+			assert.NoError(t, testGroup.Wait())
+			assert.Equal(t, tc.expectedHandled, <-handled)
+			if tc.expectedPending != nil {
+				assert.Equal(t, []int{2, 3}, *tc.expectedPending)
+			}
+		})
+	}
+}
+
+func TestSquad_DrainStrategyItemType(t *testing.T) {
+	strategy := Requeue(func(context.Context, string) error { return nil })
+
+	var typeErr *ItemTypeError
+	assert.ErrorAs(t, strategy.drain(context.Background(), []int{1}), &typeErr)
+	assert.EqualError(t, typeErr, "drain strategy expects []string, got []int")
+}
+
+func TestSquad_ShutdownReportFile(t *testing.T) {
+	errFatal := errors.New("fatal condition")
+	dir := t.TempDir()
+	jsonPath, textPath := filepath.Join(dir, "report.json"), filepath.Join(dir, "report.txt")
+
+	testGroup, err := New(
+		WithShutdownReportFile(jsonPath, ReportJSON),
+		WithShutdownReportFile(textPath, ReportText),
+		WithSubsystem(nil, func(context.Context) error { return nil }, WithSubsystemName("db")),
+	)
+	assert.NoError(t, err)
+	testGroup.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, WithTaskName("worker"))
+	testGroup.Stop(errFatal)
+	assert.ErrorIs(t, testGroup.Wait(), errFatal)
+
+	data, err := os.ReadFile(jsonPath)
+	assert.NoError(t, err)
+	var report struct {
+		Reason   string   `json:"reason"`
+		Cause    string   `json:"cause"`
+		Errors   []string `json:"errors"`
+		Members  []struct{ Name string }
+		Cleanups []struct {
+			Name string
+			Ran  bool
+		}
+	}
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, "stopped", report.Reason)
+	assert.Equal(t, errFatal.Error(), report.Cause)
+	assert.Equal(t, []string{errFatal.Error()}, report.Errors)
+	assert.Equal(t, "worker", report.Members[0].Name)
+	assert.Equal(t, "db", report.Cleanups[0].Name)
+	assert.True(t, report.Cleanups[0].Ran)
+
+	text, err := os.ReadFile(textPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(text), "reason: stopped\n")
+	assert.Contains(t, string(text), "cleanup db: ran=true")
+}
+
+func TestSquad_HostLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shutdown.lock")
+
+	var inside, overlaps atomic.Int32
+	critical := func(context.Context) error {
+		if inside.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(50 * time.Millisecond)
+		inside.Add(-1)
+		return nil
+	}
+
+	squads := make([]*Squad, 3)
+	for i := range squads {
+		testGroup, err := New(WithHostLock(path), WithCloses(critical))
+		assert.NoError(t, err)
+		squads[i] = testGroup
+	}
+
+	var wg sync.WaitGroup
+	for _, testGroup := range squads {
+		testGroup := testGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testGroup.Stop(nil)
+			assert.NoError(t, testGroup.Wait())
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, overlaps.Load())
+}
+
+func TestSquad_DrainStatus(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	var status string
+	kafkaClosed := make(chan struct{})
+	testGroup, err := New(
+		WithHealthEndpoint(addr),
+		WithManualTrigger(WithShutdownInGracePriod(time.Second)),
+		WithSubsystem(nil, func(ctx context.Context) error {
+			defer close(kafkaClosed)
+			PublishDrainPhase(ctx, "committing offsets")
+
+			resp, err := http.Get("http://" + addr + "/drain")
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			status = string(body)
+			return err
+		}, WithSubsystemName("kafka")),
+		WithSubsystem(nil, func(context.Context) error {
+			<-kafkaClosed
+			return errors.New("broken pipe")
+		}, WithSubsystemName("postgres")),
+	)
+	assert.NoError(t, err)
+	testGroup.PublishDrainPhase("http", DrainDrained)
+
+	testGroup.Stop(nil)
+	assert.Error(t, testGroup.Wait())
+	assert.Contains(t, status, "http: drained\nkafka: committing offsets\n")
+	assert.Equal(t, map[string]DrainPhase{
+		"http":     DrainDrained,
+		"kafka":    DrainClosed,
+		"postgres": DrainFailed,
+	}, testGroup.DrainStatus())
+}
+
+func TestRetry(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	failing := func(calls *int) func(context.Context) error {
+		return func(context.Context) error {
+			*calls++
+			return errUnavailable
+		}
+	}
+
+	var calls int
+	err := Retry(context.Background(), RetryPolicy{Backoff: Backoff{Initial: time.Millisecond}, MaxAttempts: 3}, failing(&calls))
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = Retry(ctx, RetryPolicy{Backoff: Backoff{Initial: time.Second}}, failing(&calls))
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 1, calls)
+
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+
+	var short, long int
+	testGroup.Run(func(ctx context.Context) error {
+		<-testGroup.Draining()
+		start := time.Now()
+		assert.ErrorIs(t, Retry(ctx, RetryPolicy{Backoff: Backoff{Initial: time.Second}}, failing(&long)), errUnavailable)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+		assert.ErrorIs(t, Retry(ctx, RetryPolicy{Backoff: Backoff{Initial: time.Millisecond}, MaxAttempts: 3}, failing(&short)), errUnavailable)
+		return nil
+	})
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, 1, long)
+	assert.Equal(t, 3, short)
+}
+
+func TestChainConnHooks(t *testing.T) {
+	assert.Nil(t, ChainConnState(nil, nil))
+	assert.Nil(t, ChainConnContext(nil))
+
+	var states []string
+	connState := ChainConnState(
+		func(_ net.Conn, state http.ConnState) { states = append(states, "user "+state.String()) },
+		nil,
+		func(_ net.Conn, state http.ConnState) { states = append(states, "squad "+state.String()) },
+	)
+	connState(nil, http.StateNew)
+	assert.Equal(t, []string{"user new", "squad new"}, states)
+
+	type key string
+	connContext := ChainConnContext(
+		func(ctx context.Context, _ net.Conn) context.Context { return context.WithValue(ctx, key("user"), 1) },
+		func(ctx context.Context, _ net.Conn) context.Context { return context.WithValue(ctx, key("squad"), 2) },
+	)
+	ctx := connContext(context.Background(), nil)
+	assert.Equal(t, 1, ctx.Value(key("user")))
+	assert.Equal(t, 2, ctx.Value(key("squad")))
+}
+
+var registerTestDriver sync.Once
+
+// testDriver is sql driver, which connections are pinged successfully unless dsn is "down".
+type testDriver struct{}
+
+func (testDriver) Open(dsn string) (driver.Conn, error) {
+	return testConn{down: dsn == "down"}, nil
+}
+
+type testConn struct{ down bool }
+
+func (c testConn) Ping(context.Context) error {
+	if c.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (testConn) Prepare(string) (driver.Stmt, error) { return nil, errors.ErrUnsupported }
+func (testConn) Close() error                        { return nil }
+func (testConn) Begin() (driver.Tx, error)           { return nil, errors.ErrUnsupported }
+
+func TestSquad_SQLPool(t *testing.T) {
+	registerTestDriver.Do(func() { sql.Register("squadtest", testDriver{}) })
+
+	down, err := sql.Open("squadtest", "down")
+	assert.NoError(t, err)
+	_, err = New(WithSQLPool(down, time.Second))
+	assert.ErrorContains(t, err, "subsystem sql: connection refused")
+
+	db, err := sql.Open("squadtest", "up")
+	assert.NoError(t, err)
+	testGroup, err := New(
+		WithSQLPool(db, time.Second, WithWaitInUse(), WithSQLPoolName("postgres")),
+		WithManualTrigger(WithShutdownInGracePriod(time.Second)),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, testGroup.Ready(context.Background()))
+
+	conn, err := db.Conn(context.Background())
+	assert.NoError(t, err)
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(released)
+		assert.NoError(t, conn.Close())
+	}()
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	<-released
+	assert.Zero(t, db.Stats().InUse)
+	assert.ErrorContains(t, db.Ping(), "database is closed")
+}
+
+func TestProfiles(t *testing.T) {
+	kubernetes, err := New(ProfileKubernetes())
+	assert.NoError(t, err)
+	assert.Equal(t, 25*time.Second, kubernetes.GracefulPeriod())
+	assert.Equal(t, 5*time.Second, kubernetes.preShutdownDelay)
+	assert.True(t, kubernetes.standardOrdering)
+
+	overridden, err := New(ProfileKubernetes(WithPreShutdownDelay(0), WithGracefulPeriod(10*time.Second)))
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, overridden.GracefulPeriod())
+	assert.Zero(t, overridden.preShutdownDelay)
+
+	_, err = New(ProfileSystemd(), WithManualTrigger())
+	assert.ErrorIs(t, err, ErrConflictingOptions)
+
+	cli, err := New(ProfileCLI())
+	assert.NoError(t, err)
+	cli.Run(func(context.Context) error { return nil })
+	assert.NoError(t, cli.Wait())
+	assert.Equal(t, ReasonCompleted, cli.ShutdownReason().Kind)
+}
+
+func TestGracefulListener(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	ln := NewGracefulListener(ctx, tcp)
+	defer ln.Close()
+
+	assert.NoError(t, ln.WaitIdle(context.Background()))
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer client.Close()
+	conn, err := ln.Accept()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ln.Active())
+
+	cancel()
+	_, err = ln.Accept()
+	assert.ErrorIs(t, err, ErrListenerStoppedAccept)
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelWait()
+	assert.ErrorIs(t, ln.WaitIdle(waitCtx), context.DeadlineExceeded)
+
+	assert.NoError(t, ln.CloseConns())
+	assert.NoError(t, ln.WaitIdle(context.Background()))
+	assert.Zero(t, ln.Active())
+	assert.ErrorIs(t, conn.Close(), net.ErrClosed)
+}
+
+func TestSquad_TrackConnections(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+
+	hijacked := make(chan struct{})
+	testGroup.RunServerWithOptions(&http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.NoError(t, err)
+			close(hijacked)
+			// NOTE: hijacked connection is held until server forcibly closes it.
+			_, _ = io.Copy(io.Discard, conn)
+		}),
+		ReadHeaderTimeout: time.Second,
+	}, ServerOptions{ShutdownTimeout: 100 * time.Millisecond, TrackConnections: true})
+
+	var client net.Conn
+	assert.Eventually(t, func() bool {
+		client, err = net.Dial("tcp", addr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer client.Close()
+	_, err = client.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+	assert.NoError(t, err)
+	<-hijacked
+
+	start := time.Now()
+	testGroup.Stop(nil)
+	assert.ErrorIs(t, testGroup.Wait(), context.DeadlineExceeded)
+
+	// NOTE: connection has been closed by server after shutdown timeout.
+	assert.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestSquad_RunServerTLS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	// NOTE: certificate of test server is provided by TLS config instead of files.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+
+	handling := make(chan struct{})
+	testGroup.RunServerTLS(&http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(handling)
+			time.Sleep(100 * time.Millisecond)
+			_, _ = io.WriteString(w, "ok")
+		}),
+		ReadHeaderTimeout: time.Second,
+		TLSConfig:         &tls.Config{Certificates: ts.TLS.Certificates, MinVersion: tls.VersionTLS12},
+	}, "", "")
+
+	var (
+		client = ts.Client()
+		body   = make(chan string, 1)
+	)
+	assert.Eventually(t, func() bool {
+		conn, err := tls.Dial("tcp", addr, client.Transport.(*http.Transport).TLSClientConfig)
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	go func() {
+		resp, err := client.Get("https://" + addr)
+		if !assert.NoError(t, err) {
+			body <- ""
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+	<-handling
+
+	// NOTE: in-flight request over TLS is completed during graceful shutdown.
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+	assert.Equal(t, "ok", <-body)
+}
+
+func TestSquad_WatchCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeTestCert(t, certFile, keyFile, 1)
+
+	certs, err := NewCertReloader(certFile, keyFile)
+	assert.NoError(t, err)
+	serial := func() int64 {
+		cert, err := certs.GetCertificate(nil)
+		assert.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(1), serial())
+
+	testGroup, err := New(WithReloader(certs.Reload))
+	assert.NoError(t, err)
+	testGroup.WatchCertificates(certs, 10*time.Millisecond)
+
+	// NOTE: renewed certificate is picked up by watcher.
+	writeTestCert(t, certFile, keyFile, 2)
+	assert.Eventually(t, func() bool { return serial() == 2 }, time.Second, 10*time.Millisecond)
+
+	// NOTE: failed reload keeps previous certificate.
+	assert.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	assert.Error(t, testGroup.Reload(context.Background()))
+	assert.Equal(t, int64(2), serial())
+
+	writeTestCert(t, certFile, keyFile, 3)
+	assert.NoError(t, testGroup.Reload(context.Background()))
+	assert.Equal(t, int64(3), serial())
+
+	testGroup.Stop(nil)
+	assert.NoError(t, testGroup.Wait())
+}
+
+func writeTestCert(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	// NOTE: modification time is moved forward, since files may be rewritten within its granularity.
+	mtime := time.Now().Add(time.Duration(serial) * time.Second)
+	assert.NoError(t, os.Chtimes(certFile, mtime, mtime))
+	assert.NoError(t, os.Chtimes(keyFile, mtime, mtime))
+}
+
+func TestSquad_WebSockets(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	testGroup, err := New(WithManualTrigger(WithGracefulPeriod(time.Second), WithShutdownTimeout(500*time.Millisecond)))
+	assert.NoError(t, err)
+	ws := testGroup.WebSockets(100 * time.Millisecond)
+
+	upgraded, rejected := make(chan struct{}), make(chan error, 1)
+	handler := ws.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			rejected <- err
+			return
+		}
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		assert.NoError(t, brw.Flush())
+		// NOTE: library closes connection itself with status 1001 Going Away.
+		assert.NoError(t, ws.OnShutdown(r, func() {
+			_, _ = conn.Write([]byte{0x88, 0x02, 0x03, 0xe9})
+			_ = conn.Close()
+		}))
+		close(upgraded)
+		_, _ = io.Copy(io.Discard, conn)
+	}))
+	testGroup.RunServer(&http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: time.Second})
+
+	var client net.Conn
+	assert.Eventually(t, func() bool {
+		client, err = net.Dial("tcp", addr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer client.Close()
+	_, err = client.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	assert.NoError(t, err)
+	<-upgraded
+	assert.ErrorIs(t, ws.OnShutdown(httptest.NewRequest(http.MethodGet, "/", nil), func() {}), ErrWebSocketNotRegistered)
+	assert.Equal(t, 1, ws.Active())
+
+	testGroup.Stop(nil)
+	assert.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+	received, err := io.ReadAll(client)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 101 Switching Protocols\r\n\r\n\x88\x02\x03\xe9", string(received))
+
+	// NOTE: upgrade during drain is rejected.
+	late := httptest.NewRequest(http.MethodGet, "/", nil)
+	late.Header.Set("Upgrade", "websocket")
+	handler.ServeHTTP(httptest.NewRecorder(), late)
+	assert.ErrorIs(t, <-rejected, ErrShuttingDown)
+
+	assert.NoError(t, testGroup.Wait())
+	assert.Zero(t, ws.Active())
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package squadtest
+
+import (
+	"os"
+	"syscall"
+)
+
+// unlocked reports error, if file is locked by flock.
+func unlocked(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return err
+	}
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
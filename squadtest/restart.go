@@ -0,0 +1,130 @@
+// Package squadtest contains helpers for testing services run by squad.
+package squadtest
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/moeryomenko/squad"
+)
+
+const (
+	defaultReleaseTimeout = 5 * time.Second
+	releasePollInterval   = 10 * time.Millisecond
+)
+
+// Service boots service under test, which is run by returned squad.
+type Service func() (*squad.Squad, error)
+
+// Option is an option that can be applied to Restart.
+type Option func(*config)
+
+// WithUptime sets how long service runs before its shutdown.
+func WithUptime(uptime time.Duration) Option {
+	return func(c *config) {
+		c.uptime = uptime
+	}
+}
+
+// WithReleaseTimeout sets how long resources may be released after shutdown.
+func WithReleaseTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.releaseTimeout = timeout
+	}
+}
+
+// WithPorts sets tcp addresses, which must be free after shutdown.
+func WithPorts(addrs ...string) Option {
+	return func(c *config) {
+		c.addrs = append(c.addrs, addrs...)
+	}
+}
+
+// WithFiles sets files, which must be unlocked after shutdown.
+func WithFiles(paths ...string) Option {
+	return func(c *config) {
+		c.files = append(c.files, paths...)
+	}
+}
+
+// Restart boots service, drives its full graceful shutdown and boots it again,
+// asserting after every shutdown that all resources were released: ports are free,
+// files are unlocked and goroutines started by service are gone.
+func Restart(tb testing.TB, service Service, opts ...Option) {
+	tb.Helper()
+
+	c := config{releaseTimeout: defaultReleaseTimeout}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	baseline := runtime.NumGoroutine()
+	for run := 1; run <= 2; run++ {
+		if err := c.run(service); err != nil {
+			tb.Fatalf("run %d: %v", run, err)
+		}
+		if err := c.released(baseline); err != nil {
+			tb.Fatalf("run %d: %v", run, err)
+		}
+	}
+}
+
+type config struct {
+	uptime         time.Duration
+	releaseTimeout time.Duration
+	addrs          []string
+	files          []string
+}
+
+func (c *config) run(service Service) error {
+	s, err := service()
+	if err != nil {
+		return fmt.Errorf("boot: %w", err)
+	}
+
+	time.Sleep(c.uptime)
+	s.Stop(nil)
+	if err := s.Wait(); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
+}
+
+// released waits until resources of service are released within release timeout.
+func (c *config) released(baseline int) error {
+	deadline := time.Now().Add(c.releaseTimeout)
+	for {
+		err := c.check(baseline)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(releasePollInterval)
+	}
+}
+
+func (c *config) check(baseline int) error {
+	for _, addr := range c.addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("port is not released: %w", err)
+		}
+		_ = ln.Close()
+	}
+	for _, path := range c.files {
+		if err := unlocked(path); err != nil {
+			return fmt.Errorf("file %s is not released: %w", path, err)
+		}
+	}
+	if leaked := runtime.NumGoroutine() - baseline; leaked > 0 {
+		return fmt.Errorf("%d goroutines are not released:\n%s", leaked, stacks())
+	}
+	return nil
+}
+
+func stacks() []byte {
+	buf := make([]byte, 1<<16)
+	return buf[:runtime.Stack(buf, true)]
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package squadtest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+func TestRestart(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	assert.NoError(t, ln.Close())
+
+	lock := filepath.Join(t.TempDir(), "lock")
+	assert.NoError(t, os.WriteFile(lock, nil, 0o600))
+
+	Restart(t, func() (*squad.Squad, error) {
+		f, err := os.Open(lock)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			return nil, err
+		}
+
+		s, err := squad.New(
+			squad.WithManualTrigger(squad.WithShutdownInGracePriod(time.Second)),
+			squad.WithCloses(func(context.Context) error { return f.Close() }),
+		)
+		if err != nil {
+			return nil, err
+		}
+		s.RunServer(&http.Server{Addr: addr, ReadHeaderTimeout: time.Second})
+		return s, nil
+	}, WithUptime(50*time.Millisecond), WithPorts(addr), WithFiles(lock))
+}
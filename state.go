@@ -0,0 +1,115 @@
+package squad
+
+import (
+	"sync"
+	"time"
+)
+
+// State is a lifecycle state of squad, states only advance in order of declaration,
+// some of them may be skipped, e.g. Draining without graceful period.
+type State int32
+
+const (
+	// StateCreated is state of squad, bootstrap of which has not begun, see WithDeferredBootstrap.
+	StateCreated State = iota
+	// StateBootstrapping is state of squad running bootstrap functions.
+	StateBootstrapping
+	// StateRunning is state of squad, bootstrap of which has been completed.
+	StateRunning
+	// StateDraining is state of squad in pre-shutdown delay or graceful period.
+	StateDraining
+	// StateStopping is state of squad, context of which has been canceled,
+	// members are stopping and cleanup functions are running.
+	StateStopping
+	// StateStopped is state of squad, Wait of which has returned.
+	StateStopped
+)
+
+func (st State) String() string {
+	switch st {
+	case StateCreated:
+		return "created"
+	case StateBootstrapping:
+		return "bootstrapping"
+	case StateRunning:
+		return "running"
+	case StateDraining:
+		return "draining"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// StateChange is a transition of squad between lifecycle states.
+type StateChange struct {
+	From, To State
+	At       time.Time
+}
+
+// lifecycle is guarded lifecycle state of squad and its subscribers.
+type lifecycle struct {
+	mtx         sync.Mutex
+	state       State
+	subscribers []chan StateChange
+}
+
+// State returns actual lifecycle state of squad.
+func (s *Squad) State() State {
+	s.lifecycle.mtx.Lock()
+	defer s.lifecycle.mtx.Unlock()
+	return s.lifecycle.state
+}
+
+// Subscribe returns channel, which receives subsequent lifecycle state changes of squad
+// in order, it is closed after squad has stopped. Channel is buffered for all changes,
+// so slow receiver does not block squad.
+func (s *Squad) Subscribe() <-chan StateChange {
+	l := &s.lifecycle
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	ch := make(chan StateChange, StateStopped-l.state)
+	if l.state == StateStopped {
+		close(ch)
+		return ch
+	}
+	l.subscribers = append(l.subscribers, ch)
+	return ch
+}
+
+// setState advances lifecycle state of squad, earlier states are ignored.
+func (s *Squad) setState(state State) {
+	if change, ok := s.advance(state); ok {
+		s.armForceExit()
+		s.runHooks(change)
+		if change.To == StateStopped {
+			s.disarmForceExit()
+		}
+	}
+}
+
+func (s *Squad) advance(state State) (StateChange, bool) {
+	l := &s.lifecycle
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if state <= l.state {
+		return StateChange{}, false
+	}
+	change := StateChange{From: l.state, To: state, At: time.Now()}
+	l.state = state
+	for _, ch := range l.subscribers {
+		ch <- change
+		if state == StateStopped {
+			close(ch)
+		}
+	}
+	if state == StateStopped {
+		l.subscribers = nil
+	}
+	return change, true
+}
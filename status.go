@@ -0,0 +1,121 @@
+package squad
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemberStatus describes liveness history of squad member.
+type MemberStatus struct {
+	Name    string
+	Running bool
+	// StartedAt is time of last start of member, zero until member has been launched.
+	StartedAt time.Time
+	// Uptime is time since last start of running member.
+	Uptime time.Duration
+	// Restarts is number of restarts of member, see PanicRestart and RunSupervised.
+	Restarts int
+	// Flaps is number of restart log intervals, within which supervised member
+	// has been restarted repeatedly, see WithRestartLogInterval.
+	Flaps int
+	// Goroutines is number of goroutines owned by member, see Squad.Goroutines.
+	Goroutines  int
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// Describe returns status of all squad members in order of launch,
+// it is safe to call while squad is running.
+func (s *Squad) Describe() []MemberStatus {
+	s.mtx.Lock()
+	members := slices.Clone(s.members)
+	s.mtx.Unlock()
+
+	goroutines := s.memberGoroutines()
+	statuses := make([]MemberStatus, 0, len(members))
+	for _, m := range members {
+		status := m.describe()
+		status.Goroutines = goroutines[status.Name]
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// serveMembers responds status of members in order of launch as text lines
+// "name: running=true restarts=0 flaps=0 goroutines=1".
+func (s *Squad) serveMembers(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, status := range s.Describe() {
+		fmt.Fprintf(w, "%s: running=%t restarts=%d flaps=%d goroutines=%d\n",
+			status.Name, status.Running, status.Restarts, status.Flaps, status.Goroutines)
+	}
+}
+
+// liveness is guarded liveness history of squad member.
+type liveness struct {
+	mtx    sync.Mutex
+	status MemberStatus
+}
+
+func (s *Squad) track(name string) *liveness {
+	l := &liveness{status: MemberStatus{Name: name}}
+
+	s.mtx.Lock()
+	s.members = append(s.members, l)
+	s.mtx.Unlock()
+
+	return l
+}
+
+func (l *liveness) started() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.status.Running, l.status.StartedAt = true, time.Now()
+}
+
+func (l *liveness) stopped(err error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.status.Running = false
+	if err != nil {
+		l.status.LastError, l.status.LastErrorAt = err, time.Now()
+	}
+}
+
+func (l *liveness) restarted() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.status.Restarts++
+}
+
+func (l *liveness) flapped() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.status.Flaps++
+}
+
+func (l *liveness) describe() MemberStatus {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	status := l.status
+	if status.Running {
+		status.Uptime = time.Since(status.StartedAt)
+	}
+	return status
+}
+
+// dumpDiagnostics logs status of members and writes stacks of all goroutines to w.
+func (s *Squad) dumpDiagnostics(w io.Writer) {
+	for _, status := range s.Describe() {
+		s.log(slog.LevelInfo, "member status", "name", status.Name, "running", status.Running,
+			"uptime", status.Uptime, "restarts", status.Restarts, "flaps", status.Flaps, "goroutines", status.Goroutines, "last_error", status.LastError)
+	}
+	_ = pprof.Lookup("goroutine").WriteTo(w, 2)
+}
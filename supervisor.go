@@ -0,0 +1,85 @@
+package squad
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// maxBackoffShift limits exponential growth of backoff.
+const maxBackoffShift = 10
+
+// RestartPolicy defines restarts of supervised member on failure.
+type RestartPolicy struct {
+	// MaxRestarts limits number of restarts, negative means unlimited.
+	MaxRestarts int
+	// Backoff is pause between restarts, zero means restart at once.
+	Backoff Backoff
+	// RestartOn reports whether member should be restarted after error,
+	// nil means restart on any error.
+	RestartOn func(error) bool
+}
+
+// RunSupervised runs the fn, which is restarted on failure by given policy.
+// Only error of exhausted policy signals all group members to stop.
+// Restarts are budget-aware same as retries of Retry.
+func (s *Squad) RunSupervised(fn func(context.Context) error, policy RestartPolicy, opts ...TaskOption) {
+	s.supervise(s.newTask(opts), fn, policy.retryPolicy())
+}
+
+// retryPolicy returns policy of Retry, which restarts member by restart policy.
+func (p RestartPolicy) retryPolicy() RetryPolicy {
+	policy := RetryPolicy{Backoff: p.Backoff, RetryOn: p.RestartOn}
+	if p.MaxRestarts >= 0 {
+		policy.MaxAttempts = p.MaxRestarts + 1
+	}
+	return policy
+}
+
+// Backoff is exponential backoff with jitter.
+type Backoff struct {
+	// Initial is pause before first retry, it is doubled for every next one.
+	Initial time.Duration
+	// Max limits pause, zero means no limit.
+	Max time.Duration
+	// Jitter is fraction of pause in range [0, 1], by which pause is randomly shortened.
+	Jitter float64
+}
+
+// delay returns pause before retry after given number of retries.
+func (b Backoff) delay(retries int) time.Duration {
+	delay := b.Initial << min(retries, maxBackoffShift)
+	if b.Max > 0 {
+		delay = min(delay, b.Max)
+	}
+	return delay - time.Duration(b.Jitter*rand.Float64()*float64(delay))
+}
+
+// RunWithRetry runs the fn, which is retried on failure with given backoff,
+// until maxAttempts attempts are exhausted, non-positive maxAttempts means unlimited.
+// Only error of last attempt signals all group members to stop.
+func (s *Squad) RunWithRetry(fn func(context.Context) error, backoff Backoff, maxAttempts int, opts ...TaskOption) {
+	s.supervise(s.newTask(opts), fn, RetryPolicy{Backoff: backoff, MaxAttempts: maxAttempts})
+}
+
+// supervise launches member, which is restarted on failure while policy allows,
+// restarts are logged by rate-limited restart log, see WithRestartLogInterval.
+func (s *Squad) supervise(t *task, fn func(context.Context) error, policy RetryPolicy) {
+	log := s.restartLog(t)
+	s.goTask(t, func(ctx context.Context) error {
+		defer log.stop()
+
+		restarting := false
+		return retry(ctx, policy, func(ctx context.Context) error {
+			if restarting {
+				t.liveness.restarted()
+				t.liveness.started()
+			}
+			restarting = true
+			return fn(ctx)
+		}, func(err error, _ int) {
+			t.liveness.stopped(err)
+			log.restarting(err)
+		})
+	})
+}
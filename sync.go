@@ -0,0 +1,93 @@
+package squad
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Semaphore is a counting semaphore, which respects drain deadline of squad.
+type Semaphore struct {
+	s            *Squad
+	slots        chan struct{}
+	minRemaining time.Duration
+}
+
+// Semaphore returns semaphore with n slots. Once squad is draining, Acquire fails
+// with ErrShuttingDown, if less than minRemaining is left until squad context is canceled,
+// so zero minRemaining allows acquiring until the end of drain.
+func (s *Squad) Semaphore(n int, minRemaining time.Duration) *Semaphore {
+	return &Semaphore{s: s, slots: make(chan struct{}, n), minRemaining: minRemaining}
+}
+
+// Acquire acquires slot of semaphore blocking until slot is available,
+// ctx is done or drain passes threshold of semaphore.
+func (sem *Semaphore) Acquire(ctx context.Context) error {
+	if err := sem.admit(); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-sem.s.ctx.Done():
+		return ErrShuttingDown
+	case sem.slots <- struct{}{}:
+		if err := sem.admit(); err != nil {
+			sem.Release()
+			return err
+		}
+		return nil
+	}
+}
+
+// Release releases acquired slot of semaphore.
+func (sem *Semaphore) Release() {
+	<-sem.slots
+}
+
+func (sem *Semaphore) admit() error {
+	state := sem.s.ShutdownState()
+	if state.Draining && (sem.s.ctx.Err() != nil || state.Remaining <= sem.minRemaining) {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// WaitGroup is a collection of goroutines run with squad context,
+// which are not launched after squad begins shutdown.
+type WaitGroup struct {
+	s  *Squad
+	wg sync.WaitGroup
+}
+
+// WaitGroup returns empty squad-aware wait group.
+func (s *Squad) WaitGroup() *WaitGroup {
+	return &WaitGroup{s: s}
+}
+
+// Go runs fn with squad context, it returns ErrShuttingDown without running fn,
+// if squad has begun shutdown.
+func (wg *WaitGroup) Go(fn func(context.Context)) error {
+	if wg.s.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+
+	wg.wg.Add(1)
+	go func() {
+		defer wg.wg.Done()
+		fn(wg.s.ctx)
+	}()
+	return nil
+}
+
+// Wait blocks until all goroutines exit or ctx is done,
+// e.g. context of cleanup function bounded by shutdown timeout.
+func (wg *WaitGroup) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-waitDone(&wg.wg):
+		return nil
+	}
+}
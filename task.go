@@ -0,0 +1,186 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"time"
+)
+
+// ErrTaskHung is reported as squad member error, when member keeps running
+// longer than its stop timeout after squad context has been canceled.
+var ErrTaskHung = errors.New("task did not stop in time")
+
+// TaskOption is an option that can be applied to squad member.
+type TaskOption func(*task)
+
+// WithTaskName sets name of squad member, which identifies it in errors.
+// By default members are named by order of launch: #1, #2, etc.
+func WithTaskName(name string) TaskOption {
+	return func(t *task) {
+		t.name = name
+	}
+}
+
+// WithStopTimeout sets how long squad member may keep running after squad context
+// has been canceled, after that member is reported as hung and abandoned.
+// Squad waits such members before running cleanup functions.
+func WithStopTimeout(timeout time.Duration) TaskOption {
+	return func(t *task) {
+		t.stopTimeout = timeout
+	}
+}
+
+// WithBackground marks squad member as background one, e.g. scheduler,
+// completion of which is not awaited by WithRunUntilComplete.
+func WithBackground() TaskOption {
+	return func(t *task) {
+		t.background = true
+	}
+}
+
+// WithCleanupTimeout sets own timeout of onDown function of squad member,
+// see Squad.RunGracefully, which is enforced within shutdown timeout.
+func WithCleanupTimeout(timeout time.Duration) TaskOption {
+	return func(t *task) {
+		t.cleanupTimeout = timeout
+	}
+}
+
+// TaskError is an error of failed squad member, which is set as cause
+// of squad context cancellation, see context.Cause.
+type TaskError struct {
+	Name string
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %s failed: %v", e.Name, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// task is configuration of squad member.
+type task struct {
+	name           string
+	stopTimeout    time.Duration
+	cleanupTimeout time.Duration
+	checkpointer   Checkpointer
+	drainStrategy  DrainStrategy
+	panicPolicy    PanicPolicy
+	liveness       *liveness
+	// background members, e.g. servers, do not complete squad, see WithRunUntilComplete.
+	background bool
+}
+
+func (s *Squad) newTask(opts []TaskOption) *task {
+	t := &task{name: "#" + strconv.FormatInt(s.tasks.Add(1), 10)}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.liveness = s.track(t.name)
+	return t
+}
+
+// goTask launches squad member, failure of which cancels squad context
+// with cause identifying member.
+func (s *Squad) goTask(t *task, fn func(context.Context) error) {
+	if s.enqueue(func() { s.goTask(t, fn) }) {
+		return
+	}
+
+	done := make(chan struct{})
+	if t.stopTimeout > 0 {
+		s.watchStop(t, done)
+	}
+	if t.checkpointer != nil && s.checkpointHandler != nil {
+		s.watchCheckpoint(t, done)
+	}
+
+	s.running.Add(1)
+	if !t.background {
+		s.jobs.Add(1)
+	}
+	go func() {
+		defer s.running.Done()
+		defer close(done)
+		if !t.background {
+			defer s.jobs.Done()
+		}
+
+		if err := s.runTask(s.ctx, t, fn); err != nil {
+			s.log(slog.LevelError, "task failed", "task", t.name, "error", err)
+			s.appendErr(err)
+			taskErr := &TaskError{Name: t.name, Err: err}
+			s.setReason(ShutdownReason{Kind: ReasonTaskFailed, Err: taskErr}, taskErr)
+			s.cancelCause(taskErr)
+		}
+	}()
+}
+
+// watchStop reports member as hung, if it does not stop in its stop timeout.
+func (s *Squad) watchStop(t *task, done <-chan struct{}) {
+	s.stopping.Add(1)
+
+	go func() {
+		defer s.stopping.Done()
+
+		select {
+		case <-done:
+			return
+		case <-s.ctx.Done():
+		}
+
+		select {
+		case <-done:
+		case <-time.After(t.stopTimeout):
+			s.log(slog.LevelWarn, "task hung", "task", t.name, "stop_timeout", t.stopTimeout)
+			s.appendErr(&TaskError{Name: t.name, Err: ErrTaskHung})
+		}
+	}()
+}
+
+// lifecycle phases of squad goroutines, see labeled.
+const (
+	phaseBootstrap = "bootstrap"
+	phaseStage     = "bootstrap.stage"
+	phaseRun       = "run"
+	phaseDrain     = "drain"
+	phaseCleanup   = "cleanup"
+)
+
+// labelFunc runs fn on behalf of squad member in given lifecycle phase.
+type labelFunc func(ctx context.Context, member, phase string, fn func(context.Context) error) error
+
+// labeled runs fn with pprof labels of squad member and lifecycle phase,
+// so goroutine and CPU profiles show which member owns goroutines,
+// and within execution trace region of the phase.
+func (s *Squad) labeled(ctx context.Context, member, phase string, fn func(context.Context) error) (err error) {
+	if s.lowOverhead {
+		return fn(ctx)
+	}
+
+	pprof.Do(ctx, pprof.Labels(memberLabel, member, "squad.phase", phase), func(ctx context.Context) {
+		trace.Log(ctx, "squad.member", member)
+		trace.WithRegion(ctx, "squad."+phase, func() {
+			err = fn(ctx)
+		})
+	})
+	return err
+}
+
+// traceTask creates execution trace task of lifecycle phase.
+func (s *Squad) traceTask(ctx context.Context, name string) (context.Context, func()) {
+	if s.lowOverhead {
+		return ctx, func() {}
+	}
+
+	ctx, task := trace.NewTask(ctx, name)
+	return ctx, task.End
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package upgrade
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// supported reports whether listeners can be passed to new process on this platform.
+const supported = false
+
+var upgradeSignals []os.Signal
+
+func startProcess(string, []string, []syscall.Conn, *os.File) (*os.Process, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func keepSocketFile(net.Listener) {}
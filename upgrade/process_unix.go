@@ -0,0 +1,65 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// supported reports whether listeners can be passed to new process on this platform.
+const supported = true
+
+var upgradeSignals = []os.Signal{syscall.SIGUSR2}
+
+// startProcess starts binary with same arguments, descriptors of listeners are passed
+// starting from firstFD followed by extra file. Descriptors are passed as is, since
+// os.File of listener would switch shared socket to blocking mode, then Accept of old
+// process could not be interrupted by closing of listener during drain.
+func startProcess(exe string, env []string, conns []syscall.Conn, extra *os.File) (*os.Process, error) {
+	fds := []uintptr{os.Stdin.Fd(), os.Stdout.Fd(), os.Stderr.Fd()}
+
+	var (
+		pid   int
+		start func(conns []syscall.Conn) error
+	)
+	// NOTE: descriptors are valid only within Control of their listeners.
+	start = func(conns []syscall.Conn) error {
+		if len(conns) == 0 {
+			var err error
+			pid, err = syscall.ForkExec(exe, os.Args, &syscall.ProcAttr{
+				Env:   env,
+				Files: append(fds, extra.Fd()),
+			})
+			return err
+		}
+
+		raw, err := conns[0].SyscallConn()
+		if err != nil {
+			return err
+		}
+		var startErr error
+		err = raw.Control(func(fd uintptr) {
+			fds = append(fds, fd)
+			startErr = start(conns[1:])
+		})
+		if err != nil {
+			return err
+		}
+		return startErr
+	}
+
+	if err := start(conns); err != nil {
+		return nil, err
+	}
+	return os.FindProcess(pid)
+}
+
+// keepSocketFile keeps socket file of unix listener on close,
+// since it is served by new process after old one closes listener.
+func keepSocketFile(ln net.Listener) {
+	if ul, ok := ln.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+}
@@ -0,0 +1,319 @@
+// Package upgrade contains zero-downtime restart of service run by squad: new process
+// of binary inherits listening sockets, so old process drains without dropping connections,
+// e.g. for bare-metal deployments without load balancer.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/moeryomenko/squad"
+)
+
+const (
+	// envListeners is list of inherited listeners, which are passed starting from firstFD.
+	envListeners = "SQUAD_UPGRADE_LISTENERS"
+	// envReady is file descriptor of pipe, which is closed by child when it is ready.
+	envReady = "SQUAD_UPGRADE_READY"
+	firstFD  = 3
+
+	defaultReadyTimeout = time.Minute
+)
+
+var (
+	// ErrUpgradeInProgress is returned by Upgrade, if previous upgrade has not been completed.
+	ErrUpgradeInProgress = errors.New("upgrade is in progress")
+	// ErrChildExited is returned by Upgrade, if new process exited before it was ready.
+	ErrChildExited = errors.New("new process exited before ready")
+)
+
+// Option is an option that can be applied to Upgrader.
+type Option func(*Upgrader)
+
+// WithReadyTimeout sets how long new process may boot, after that it is killed
+// and old process keeps serving. Default is one minute.
+func WithReadyTimeout(timeout time.Duration) Option {
+	return func(u *Upgrader) {
+		u.readyTimeout = timeout
+	}
+}
+
+// WithSignals sets signals, which trigger upgrade, default is SIGUSR2.
+func WithSignals(signals ...os.Signal) Option {
+	return func(u *Upgrader) {
+		u.signals = signals
+	}
+}
+
+// WithOnError sets handler of failed upgrades triggered by signal,
+// failed upgrade does not stop squad.
+func WithOnError(onErr func(error)) Option {
+	return func(u *Upgrader) {
+		u.onErr = onErr
+	}
+}
+
+// Upgrader passes listeners to new process of binary, e.g.
+//
+//	u, err := upgrade.New(s)
+//	ln, err := u.Listen("tcp", ":8080")
+//	s.RunServerWithOptions(srv, squad.ServerOptions{Listener: ln})
+//	err = u.Ready()
+//
+// On signal it starts binary again with same arguments and listeners, waits until new process
+// calls Ready and then stops squad, so old process drains accepted connections, while
+// new one accepts new connections on the same sockets.
+type Upgrader struct {
+	s            *squad.Squad
+	readyTimeout time.Duration
+	signals      []os.Signal
+	onErr        func(error)
+
+	// guarded listeners inherited from parent and ones passed to child.
+	mtx       sync.Mutex
+	inherited map[string]net.Listener
+	listeners []listener
+	upgrading bool
+	parent    *os.File
+}
+
+type listener struct {
+	name string
+	ln   net.Listener
+}
+
+// New returns Upgrader of squad, which takes listeners inherited from parent process,
+// if any, and watches upgrade signals until squad context is canceled.
+func New(s *squad.Squad, opts ...Option) (*Upgrader, error) {
+	u := &Upgrader{s: s, readyTimeout: defaultReadyTimeout, signals: upgradeSignals}
+	for _, opt := range opts {
+		opt(u)
+	}
+	if err := u.inherit(); err != nil {
+		return nil, err
+	}
+
+	s.Run(u.watch, squad.WithTaskName("upgrade"), squad.WithBackground())
+	return u, nil
+}
+
+// watch upgrades on signals, inherited listeners not taken by Listen are closed on exit.
+func (u *Upgrader) watch(ctx context.Context) error {
+	defer u.closeInherited()
+	if len(u.signals) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, u.signals...)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sigs:
+			if err := u.Upgrade(ctx); err != nil && u.onErr != nil {
+				u.onErr(err)
+			}
+		}
+	}
+}
+
+// inherit takes listeners and readiness pipe passed by parent process.
+func (u *Upgrader) inherit() error {
+	names, ready := os.Getenv(envListeners), os.Getenv(envReady)
+	_, _ = os.Unsetenv(envListeners), os.Unsetenv(envReady)
+	if ready == "" {
+		return nil
+	}
+
+	fd, err := strconv.Atoi(ready)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", envReady, err)
+	}
+	u.parent = os.NewFile(uintptr(fd), "upgrade ready")
+
+	u.inherited = make(map[string]net.Listener)
+	if names == "" {
+		return nil
+	}
+	for i, name := range strings.Split(names, ",") {
+		f := os.NewFile(uintptr(firstFD+i), name)
+		ln, err := net.FileListener(f)
+		err = errors.Join(err, f.Close())
+		if err != nil {
+			u.closeInherited()
+			return fmt.Errorf("inherited listener %s: %w", name, err)
+		}
+		u.inherited[name] = ln
+	}
+	return nil
+}
+
+// Listen returns listener inherited from parent process, which has requested the same
+// network and address, e.g. ":0" too, otherwise it announces on the local network address
+// like net.Listen. Socket file of unix listener is not removed on close.
+// Listener is passed to new process on upgrade.
+func (u *Upgrader) Listen(network, address string) (net.Listener, error) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	name := network + ":" + address
+	ln, ok := u.inherited[name]
+	if ok {
+		delete(u.inherited, name)
+	} else {
+		var err error
+		if ln, err = net.Listen(network, address); err != nil {
+			return nil, err
+		}
+	}
+	keepSocketFile(ln)
+
+	u.listeners = append(u.listeners, listener{name: name, ln: ln})
+	return ln, nil
+}
+
+// Ready reports parent process that new process is ready to serve, so parent
+// begins shutdown. It does nothing if process has not been started by upgrade.
+func (u *Upgrader) Ready() error {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if u.parent == nil {
+		return nil
+	}
+	_, err := u.parent.Write([]byte{1})
+	err = errors.Join(err, u.parent.Close())
+	u.parent = nil
+	return err
+}
+
+// Upgrade starts new process of binary with listeners and waits until it is ready,
+// then stops squad. If new process fails to become ready in ready timeout, it is killed
+// and old process keeps serving.
+func (u *Upgrader) Upgrade(ctx context.Context) error {
+	if !supported {
+		return errors.ErrUnsupported
+	}
+
+	conns, names, err := u.begin()
+	if err != nil {
+		return err
+	}
+	defer u.end()
+
+	proc, pipe, err := u.start(conns, names)
+	if err != nil {
+		return err
+	}
+	ready := make(chan error, 1)
+	go func() {
+		defer pipe.Close()
+		// NOTE: exit of child closes pipe without notification.
+		if _, err := pipe.Read(make([]byte, 1)); err != nil {
+			ready <- ErrChildExited
+			return
+		}
+		ready <- nil
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, u.readyTimeout)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case err = <-ready:
+	}
+	if err != nil {
+		_ = proc.Kill()
+		if exitErr := exited(proc.Wait()); errors.Is(err, ErrChildExited) {
+			err = errors.Join(err, exitErr)
+		}
+		return err
+	}
+
+	u.s.Stop(nil)
+	return proc.Release()
+}
+
+// begin marks upgrade in progress and returns listeners to pass.
+func (u *Upgrader) begin() ([]syscall.Conn, []string, error) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	if u.upgrading {
+		return nil, nil, ErrUpgradeInProgress
+	}
+
+	conns, names := make([]syscall.Conn, 0, len(u.listeners)), make([]string, 0, len(u.listeners))
+	for _, l := range u.listeners {
+		conn, ok := l.ln.(syscall.Conn)
+		if !ok {
+			return nil, nil, fmt.Errorf("listener %s: %w", l.name, errors.ErrUnsupported)
+		}
+		conns, names = append(conns, conn), append(names, l.name)
+	}
+
+	u.upgrading = true
+	return conns, names, nil
+}
+
+func (u *Upgrader) end() {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	u.upgrading = false
+}
+
+// start starts new process, which notifies readiness by returned pipe.
+func (u *Upgrader) start(conns []syscall.Conn, names []string) (*os.Process, *os.File, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env := append(os.Environ(),
+		envListeners+"="+strings.Join(names, ","),
+		envReady+"="+strconv.Itoa(firstFD+len(conns)),
+	)
+	proc, err := startProcess(exe, env, conns, w)
+	_ = w.Close()
+	if err != nil {
+		_ = r.Close()
+		return nil, nil, err
+	}
+	return proc, r, nil
+}
+
+// exited returns error of process exit status, if it is not successful.
+func exited(state *os.ProcessState, err error) error {
+	if err != nil || state.Success() {
+		return err
+	}
+	return errors.New(state.String())
+}
+
+func (u *Upgrader) closeInherited() {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	for name, ln := range u.inherited {
+		_ = ln.Close()
+		delete(u.inherited, name)
+	}
+}
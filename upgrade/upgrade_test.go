@@ -0,0 +1,104 @@
+package upgrade
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moeryomenko/squad"
+)
+
+// envChild makes test binary act as new process of upgraded service.
+const envChild = "UPGRADE_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(envChild) {
+	case "":
+		os.Exit(m.Run())
+	case "serve":
+		os.Exit(serveChild())
+	default:
+		// NOTE: child fails before it is ready.
+		os.Exit(1)
+	}
+}
+
+// serveChild serves listener inherited for the same address until it handles one request.
+func serveChild() int {
+	s, err := squad.New(squad.WithManualTrigger(squad.WithShutdownInGracePriod(time.Second)))
+	if err != nil {
+		return 1
+	}
+	u, err := New(s, WithSignals())
+	if err != nil {
+		return 1
+	}
+	ln, err := u.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 1
+	}
+
+	srv := &http.Server{ReadHeaderTimeout: time.Second, Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "child")
+		s.Stop(nil)
+	})}
+	s.RunShutdowner(func(context.Context) error { return srv.Serve(ln) }, srv)
+	time.AfterFunc(5*time.Second, func() { s.Stop(nil) })
+	if err := u.Ready(); err != nil {
+		return 1
+	}
+	if err := s.Wait(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestUpgrade(t *testing.T) {
+	s, err := squad.New(squad.WithManualTrigger(squad.WithShutdownInGracePriod(time.Second)))
+	assert.NoError(t, err)
+	u, err := New(s, WithSignals(), WithReadyTimeout(5*time.Second))
+	assert.NoError(t, err)
+	// NOTE: not upgraded process has no parent to notify.
+	assert.NoError(t, u.Ready())
+
+	ln, err := u.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	srv := &http.Server{ReadHeaderTimeout: time.Second, Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "parent")
+	})}
+	s.RunShutdowner(func(context.Context) error { return srv.Serve(ln) }, srv)
+	assert.Equal(t, "parent", get(t, addr))
+
+	t.Setenv(envChild, "fail")
+	assert.ErrorIs(t, u.Upgrade(context.Background()), ErrChildExited)
+	assert.Equal(t, "parent", get(t, addr))
+
+	t.Setenv(envChild, "serve")
+	if !assert.NoError(t, u.Upgrade(context.Background())) {
+		return
+	}
+	assert.NoError(t, s.Wait())
+
+	// NOTE: socket is served by new process after old one has stopped.
+	assert.Equal(t, "child", get(t, addr))
+}
+
+func get(t *testing.T, addr string) string {
+	t.Helper()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
@@ -0,0 +1,55 @@
+package squad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrWarmingUp is returned by Squad.Ready during warmup window, see WithWarmup.
+var ErrWarmingUp = errors.New("squad is warming up")
+
+// WithWarmup is a Squad option that keeps squad not ready for warmup period
+// after bootstrap has been completed, so caches and connection pools reach
+// steady state before load balancer sends full traffic. Warmup ends earlier,
+// once optional check reports warm state by returning nil.
+func WithWarmup(period time.Duration, check func(context.Context) error) Option {
+	return func(s *Squad) {
+		s.warmup = &warmup{period: period, check: check}
+	}
+}
+
+// warmup is a readiness delay after bootstrap.
+type warmup struct {
+	period time.Duration
+	check  func(context.Context) error
+}
+
+// startWarmup begins warmup window.
+func (s *Squad) startWarmup() {
+	if s.warmup != nil {
+		s.warmupUntil.Store(time.Now().Add(s.warmup.period).UnixNano())
+	}
+}
+
+// warmedUp reports error, while squad is warming up.
+func (s *Squad) warmedUp(ctx context.Context) error {
+	if s.warmup == nil || s.warmed.Load() {
+		return nil
+	}
+
+	until := s.warmupUntil.Load()
+	switch {
+	case until == 0:
+		return ErrWarmingUp
+	case time.Now().UnixNano() < until && s.warmup.check == nil:
+		return ErrWarmingUp
+	case time.Now().UnixNano() < until:
+		if err := s.warmup.check(ctx); err != nil {
+			return fmt.Errorf("%w: %w", ErrWarmingUp, err)
+		}
+	}
+	s.warmed.Store(true)
+	return nil
+}
@@ -0,0 +1,207 @@
+package squad
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrWebSocketNotRegistered is returned by WebSockets.OnShutdown, when connection
+// of request has not been hijacked through WebSockets.Middleware.
+var ErrWebSocketNotRegistered = errors.New("websocket connection is not registered")
+
+// WebSockets is registry of websocket connections hijacked from http servers,
+// which are ignored by http.Server.Shutdown. When squad begins shutdown, close callbacks
+// of registered connections are called, so websocket library closes them itself, e.g.
+// with status 1001 Going Away, see WebSockets.OnShutdown. After wait period connections,
+// which have not been closed yet, including ones without close callback, are closed
+// forcibly without close frame. Upgrades after beginning of shutdown are rejected.
+// Squad stops consumers and runs cleanup functions after that, same as for servers.
+type WebSockets struct {
+	wait time.Duration
+
+	mtx      sync.Mutex
+	draining bool
+	conns    map[*wsConn]struct{}
+	// idle is closed while there are no registered connections.
+	idle chan struct{}
+}
+
+// WebSockets returns registry of websocket connections, which are closed
+// within wait period after squad begins shutdown, see WebSockets.Middleware.
+func (s *Squad) WebSockets(wait time.Duration) *WebSockets {
+	idle := make(chan struct{})
+	close(idle)
+	ws := &WebSockets{wait: wait, conns: make(map[*wsConn]struct{}), idle: idle}
+
+	s.servers.Add(1)
+	go func() {
+		defer s.servers.Done()
+		<-s.Draining()
+		s.log(slog.LevelInfo, "closing websockets", "active", ws.Active())
+		ws.drain()
+	}()
+	return ws
+}
+
+// Middleware registers connections of websocket upgrade requests hijacked by next handler,
+// e.g. by gorilla/websocket or nhooyr.io/websocket. Hijack of upgrade request fails
+// with ErrShuttingDown after squad has begun shutdown.
+func (ws *WebSockets) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		session := &wsSession{}
+		r = r.WithContext(context.WithValue(r.Context(), wsSessionKey{}, session))
+		next.ServeHTTP(&hijackWriter{ResponseWriter: w, ws: ws, session: session}, r)
+	})
+}
+
+// OnShutdown registers close callback of websocket connection of given upgrade request,
+// which is called when squad begins shutdown, e.g.
+//
+//	conn, err := websocket.Accept(w, r, nil)
+//	...
+//	if err := ws.OnShutdown(r, func() { conn.Close(websocket.StatusGoingAway, "shutdown") }); err != nil {
+//		conn.Close(websocket.StatusGoingAway, "shutdown")
+//		return
+//	}
+//
+// Callback is called in own goroutine and should not outlast wait period. OnShutdown
+// returns ErrShuttingDown, if squad has already begun shutdown, so caller closes
+// connection itself, or ErrWebSocketNotRegistered, if connection has not been hijacked
+// through Middleware.
+func (ws *WebSockets) OnShutdown(r *http.Request, closeFn func()) error {
+	session, ok := r.Context().Value(wsSessionKey{}).(*wsSession)
+	if !ok {
+		return ErrWebSocketNotRegistered
+	}
+
+	ws.mtx.Lock()
+	defer ws.mtx.Unlock()
+	if ws.draining {
+		return ErrShuttingDown
+	}
+	if session.conn == nil {
+		return ErrWebSocketNotRegistered
+	}
+	session.conn.closeFn = closeFn
+	return nil
+}
+
+// Active returns number of registered connections, which have not been closed yet.
+func (ws *WebSockets) Active() int {
+	ws.mtx.Lock()
+	defer ws.mtx.Unlock()
+	return len(ws.conns)
+}
+
+// drain calls close callbacks and closes remaining connections after wait period.
+func (ws *WebSockets) drain() {
+	ws.mtx.Lock()
+	// NOTE: connections are not registered after this point, so none of them is missed.
+	ws.draining = true
+	for c := range ws.conns {
+		if c.closeFn != nil {
+			go c.closeFn()
+		}
+	}
+	idle := ws.idle
+	ws.mtx.Unlock()
+
+	timer := time.NewTimer(ws.wait)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return
+	case <-timer.C:
+	}
+
+	ws.mtx.Lock()
+	conns := make([]*wsConn, 0, len(ws.conns))
+	for c := range ws.conns {
+		conns = append(conns, c)
+	}
+	ws.mtx.Unlock()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+}
+
+// add registers hijacked connection unless squad has begun shutdown.
+func (ws *WebSockets) add(session *wsSession, hijack func() (net.Conn, *bufio.ReadWriter, error)) (net.Conn, *bufio.ReadWriter, error) {
+	ws.mtx.Lock()
+	defer ws.mtx.Unlock()
+	if ws.draining {
+		return nil, nil, ErrShuttingDown
+	}
+
+	conn, brw, err := hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &wsConn{Conn: conn, ws: ws}
+	if len(ws.conns) == 0 {
+		ws.idle = make(chan struct{})
+	}
+	ws.conns[c] = struct{}{}
+	session.conn = c
+	return c, brw, nil
+}
+
+func (ws *WebSockets) remove(c *wsConn) {
+	ws.mtx.Lock()
+	defer ws.mtx.Unlock()
+	if _, ok := ws.conns[c]; !ok {
+		return
+	}
+	delete(ws.conns, c)
+	if len(ws.conns) == 0 {
+		close(ws.idle)
+	}
+}
+
+// wsSessionKey is context key of websocket session, which is carried by upgrade request.
+type wsSessionKey struct{}
+
+// wsSession binds upgrade request to its registered connection, it is guarded by mtx of registry.
+type wsSession struct {
+	conn *wsConn
+}
+
+// hijackWriter registers hijacked connection in websockets registry.
+type hijackWriter struct {
+	http.ResponseWriter
+	ws      *WebSockets
+	session *wsSession
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ws.add(w.session, http.NewResponseController(w.ResponseWriter).Hijack)
+}
+
+func (w *hijackWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wsConn is a websocket connection registered in registry,
+// closeFn is guarded by mtx of registry.
+type wsConn struct {
+	net.Conn
+	ws      *WebSockets
+	closeFn func()
+}
+
+func (c *wsConn) Close() error {
+	err := c.Conn.Close()
+	c.ws.remove(c)
+	return err
+}